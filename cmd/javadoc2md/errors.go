@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/dburkart/javadoc2md/internal/logger"
+	"github.com/dburkart/javadoc2md/internal/parser"
+)
+
+// Exit statuses, besides 0 for success
+const (
+	exitProblems = 1 // Lint found problems, or coverage is below the threshold
+	exitUsage    = 2 // The flags given don't make sense
+	exitErrors   = 3 // Files couldn't be read, or their documentation written
+)
+
+// fatal reports err, and exits with the given status.
+func fatal(status int, err error) {
+	printError(err)
+	os.Exit(status)
+}
+
+// diagnose describes err as a diagnostic, naming the file it concerns if
+// there is one.
+func diagnose(err error) logger.Diagnostic {
+	d := logger.Diagnostic{Level: logger.LOG_LEVEL_ERROR, Message: err.Error()}
+
+	var fileErr *parser.FileError
+	if errors.As(err, &fileErr) {
+		d.Code = fileErr.Code()
+		d.File = fileErr.Path
+		d.Message = "could not " + fileErr.Op + ": " + fileErr.Err.Error()
+	}
+
+	return d
+}
+
+// fileError describes a problem with the file at path, without repeating
+// the path, as the errors from package os do.
+func fileError(op string, path string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		path, err = pathErr.Path, pathErr.Err
+	}
+
+	return &parser.FileError{Op: op, Path: path, Err: err}
+}
+
+// An errorReport collects the errors met along the way, so that they can be
+// reported together at the end. With -fail-fast, the first one ends the run
+// instead.
+type errorReport struct {
+	mutex    sync.Mutex
+	failFast bool
+	errors   []error
+}
+
+func (r *errorReport) add(err error) {
+	if r.failFast {
+		fatal(exitErrors, err)
+	}
+
+	var list parser.ErrorList
+	if !errors.As(err, &list) {
+		list = parser.ErrorList{err}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.errors = append(r.errors, list...)
+}
+
+// exitIfFailed reports every error collected, in order, and exits if there
+// were any.
+func (r *errorReport) exitIfFailed() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.errors) == 0 {
+		return
+	}
+
+	sort.SliceStable(r.errors, func(i, j int) bool {
+		return r.errors[i].Error() < r.errors[j].Error()
+	})
+
+	for _, err := range r.errors {
+		printError(err)
+	}
+
+	if len(r.errors) == 1 {
+		logger.Info("1 error")
+	} else {
+		logger.Info(fmt.Sprintf("%d errors", len(r.errors)))
+	}
+	os.Exit(exitErrors)
+}
+
+// printError reports err as soon as it's met, for commands which carry on
+// running, such as -watch.
+func printError(err error) {
+	logger.Report(diagnose(err))
+}
@@ -7,9 +7,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
 	"sync"
 
 	"github.com/dburkart/javadoc2md/internal/logger"
@@ -21,39 +25,292 @@ func main() {
 	var outputDirectory string
 	var inputDirectory string
 	var skipPrivateDefs bool
+	var templateDirectory string
+	var writeIndex bool
+	var flagUndocumented bool
+	var flavorName string
+	var format string
+	var manSection string
+	var manClasses string
+	var manAnnotation string
+	var externalLibraries parser.ExternalLibraryFlag
+	var sourceURL string
+	var sourceRepo string
+	var sourceRev string
+	var lintFormat string
+	var coverageFormat string
+	var coverageThreshold float64
+	var jobs int
+	var cacheDirectory string
+	var watchInput bool
+	var address string
+	var failFast bool
+	var verbose bool
+	var quiet bool
+	var logFormat string
 
 	flag.StringVar(&inputDirectory, "input", ".", "Input directory to transpile")
-	flag.StringVar(&outputDirectory, "output", ".", "Output directory to receive markdown files")
-	skipPrivateDefs = *flag.Bool("skip-private", false, "Skip private definitions")
+	flag.StringVar(&outputDirectory, "output", ".", "Output directory to receive generated files")
+	flag.BoolVar(&skipPrivateDefs, "skip-private", false, "Skip private definitions")
+	flag.StringVar(&templateDirectory, "templates", "", "Directory containing templates which override the default layout")
+	flag.BoolVar(&writeIndex, "index", false, "Also write an index page listing every class")
+	flag.BoolVar(&flagUndocumented, "flag-undocumented", false, "Mark classes and members which have no Javadoc comment as undocumented")
+	flag.StringVar(&format, "format", "markdown", "Output format (markdown, asciidoc, html, man or json)")
+	flag.StringVar(&flavorName, "flavor", "docusaurus", "Flavor of markdown to emit (docusaurus, mdx, mkdocs or gfm)")
+	flag.Var(&externalLibraries, "link", "Resolve links against external Javadoc, given as `URL=PATH` where PATH is its element-list or package-list (repeatable)")
+	flag.StringVar(&sourceURL, "source-url", "", "Link each class and member to its source, given as a `URL` template with {repo}, {rev}, {path} and {line} (i.e. https://github.com/{repo}/blob/{rev}/{path}#L{line})")
+	flag.StringVar(&sourceRepo, "source-repo", "", "Repository substituted for {repo} in -source-url")
+	flag.StringVar(&sourceRev, "source-rev", "", "Revision substituted for {rev} in -source-url (default: the commit checked out in the input's git repository)")
+	flag.StringVar(&lintFormat, "lint-format", "text", "Format of the lint report (text or sarif)")
+	flag.StringVar(&coverageFormat, "coverage-format", "text", "Format of the coverage report (text, json or cobertura)")
+	flag.Float64Var(&coverageThreshold, "coverage-threshold", 0, "Fail if less than this `percent`age of the public API is documented")
+	flag.IntVar(&jobs, "jobs", 0, "Number of files to read and parse at once (default: the number of CPUs)")
+	flag.BoolVar(&watchInput, "watch", false, "Keep running, and update the documentation whenever a file in -input changes")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop at the first file which can't be read or documented, rather than carrying on with the rest")
+	flag.BoolVar(&verbose, "v", false, "Log debugging messages too")
+	flag.BoolVar(&quiet, "q", false, "Only log errors, not warnings")
+	flag.StringVar(&logFormat, "log-format", "text", "Format of the diagnostics written to stderr (text or json)")
+	flag.StringVar(&address, "addr", "localhost:8000", "Address to serve the preview on")
+	flag.StringVar(&cacheDirectory, "cache", "", "Directory to cache parsed files in, so that unchanged files aren't parsed again")
+	flag.StringVar(&manSection, "man-section", "1", "Section of the manual to write man pages for")
+	flag.StringVar(&manClasses, "man-classes", "", "Comma-separated list of classes to write man pages for")
+	flag.StringVar(&manAnnotation, "man-annotation", "Command", "Write man pages for classes with this annotation")
 
-	flag.Parse()
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "Usage:")
+		fmt.Fprintln(out, "  javadoc2md [flags]             Write documentation for every Java file in -input")
+		fmt.Fprintln(out, "  javadoc2md lint [flags]        Report problems with the documentation, without writing it")
+		fmt.Fprintln(out, "  javadoc2md coverage [flags]    Report how much of the public API is documented")
+		fmt.Fprintln(out, "  javadoc2md serve [flags]       Preview the documentation as a site, updated as files change")
+		fmt.Fprintln(out, "\nFlags:")
+		flag.PrintDefaults()
+	}
 
-	logger.Initialize()
+	// The lint and coverage commands report on the documentation, and the
+	// serve command previews it, rather than writing it
+	command := ""
+	if len(os.Args) > 1 && (os.Args[1] == "lint" || os.Args[1] == "coverage" || os.Args[1] == "serve") {
+		command = os.Args[1]
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
+
+	configureLogger(verbose, quiet, logFormat)
+
+	flavor, err := parser.FindFlavor(format, flavorName)
+	if err != nil {
+		fatal(exitUsage, err)
+	}
+
+	if jobs < 0 {
+		fatal(exitUsage, errors.New("-jobs can't be negative"))
+	} else if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	var cache *parser.Cache
+	if cacheDirectory != "" {
+		cache, err = parser.OpenCache(cacheDirectory)
+		if err != nil {
+			fatal(exitUsage, err)
+		}
+	}
+
+	// Commands which carry on running report errors as they go, rather than
+	// all together at the end
+	errs := &errorReport{failFast: failFast}
+	report := errs.add
+	if watchInput || command == "serve" {
+		report = printError
+	}
 
 	ctx := util.FileSearch(inputDirectory)
-	documents := make(chan *parser.Document)
-	var wg sync.WaitGroup
+	documents := parseFiles(ctx.Files, jobs, cache, report)
 
-	for {
-		fileToParse, ok := <-ctx.Files
+	// Directories which couldn't be searched are only known once every file
+	// has been found
+	documents = afterSearch(ctx, documents, report)
 
-		if !ok {
-			break
+	options := parser.VisitorConfigOptions{
+		OutputDirectory:   outputDirectory,
+		SkipPrivateDefs:   skipPrivateDefs,
+		TemplateDirectory: templateDirectory,
+		WriteIndex:        writeIndex,
+		FlagUndocumented:  flagUndocumented,
+		Flavor:            flavor,
+		ManSection:        manSection,
+		ManAnnotation:     manAnnotation,
+		ExternalLibraries: externalLibraries,
+		FailFast:          failFast,
+	}
+
+	if manClasses != "" {
+		options.ManClasses = strings.Split(manClasses, ",")
+	}
+
+	if sourceURL != "" {
+		linker := &parser.SourceLinker{Template: sourceURL, Repo: sourceRepo, Rev: sourceRev, Root: inputDirectory}
+
+		// Paths are relative to the root of the repository, when there is one
+		root, rev, err := parser.FindRepository(inputDirectory)
+		if err == nil {
+			linker.Root = root
+			if linker.Rev == "" {
+				linker.Rev = rev
+			}
+		} else if linker.Rev == "" && strings.Contains(sourceURL, "{rev}") {
+			fatal(exitUsage, err)
+		}
+
+		options.SourceLinker = linker
+	}
+
+	switch command {
+	case "lint":
+		findings := parser.LintDocuments(&options, documents)
+
+		switch lintFormat {
+		case "text":
+			err = parser.WriteLintText(os.Stdout, findings)
+		case "sarif":
+			// Files are given relative to the root of the repository, where
+			// code review tools expect them
+			root, _, repoErr := parser.FindRepository(inputDirectory)
+			if repoErr != nil {
+				root = "."
+			}
+			err = parser.WriteLintSARIF(os.Stdout, findings, root)
+		default:
+			err = fmt.Errorf("unknown lint format %q", lintFormat)
 		}
 
-		content, err := ioutil.ReadFile(fileToParse)
 		if err != nil {
-			fmt.Println(err)
+			fatal(exitUsage, err)
+		}
+
+		errs.exitIfFailed()
+		if len(findings) > 0 {
+			os.Exit(exitProblems)
+		}
+		return
+	case "coverage":
+		coverage := parser.MeasureCoverage(&options, documents)
+
+		switch coverageFormat {
+		case "text":
+			err = parser.WriteCoverageText(os.Stdout, coverage)
+		case "json":
+			err = parser.WriteCoverageJSON(os.Stdout, coverage)
+		case "cobertura":
+			err = parser.WriteCoverageCobertura(os.Stdout, coverage, inputDirectory)
+		default:
+			err = fmt.Errorf("unknown coverage format %q", coverageFormat)
+		}
+
+		if err != nil {
+			fatal(exitUsage, err)
+		}
+
+		errs.exitIfFailed()
+		if overall := coverage.Overall(); overall.Percent() < coverageThreshold {
+			logger.Report(logger.Diagnostic{
+				Level:   logger.LOG_LEVEL_ERROR,
+				Code:    "coverage-below-threshold",
+				Message: fmt.Sprintf("coverage of %.1f%% is below the threshold of %.1f%%", overall.Percent(), coverageThreshold),
+			})
+			os.Exit(exitProblems)
+		}
+		return
+	case "serve":
+		if err := serve(&options, documents, inputDirectory, jobs, cache, address); err != nil {
+			fatal(exitUsage, err)
 		}
+		return
+	}
+
+	if watchInput {
+		watch(&options, documents, inputDirectory, jobs, cache)
+		return
+	}
+
+	if err := parser.VisitDocuments(&options, documents); err != nil {
+		errs.add(err)
+	}
+	errs.exitIfFailed()
+}
+
+// configureLogger sets the level and format of diagnostics from the flags
+// given. Without -v or -q, the level is taken from the LOG_LEVEL environment
+// variable.
+func configureLogger(verbose bool, quiet bool, format string) {
+	logger.Initialize()
+
+	level := logger.Level()
+	if verbose && quiet {
+		fatal(exitUsage, errors.New("-v and -q can't be used together"))
+	} else if verbose {
+		level = logger.LOG_LEVEL_DEBUG
+	} else if quiet {
+		level = logger.LOG_LEVEL_ERROR
+	}
+
+	logFormat, err := logger.FormatForString(format)
+	if err != nil {
+		fatal(exitUsage, err)
+	}
 
+	logger.Configure(level, logFormat)
+}
+
+// parseFiles reads and parses each of the given files, with the given number
+// of workers, sending every document on the returned channel. Documents are
+// sent in whatever order they finish in. Files which haven't changed since
+// they were cached aren't parsed again, and files which can't be read are
+// reported, and skipped.
+func parseFiles(files chan string, jobs int, cache *parser.Cache, report func(error)) chan *parser.Document {
+	documents := make(chan *parser.Document)
+	var wg sync.WaitGroup
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
 		go func() {
-			s := parser.BeginScanningJavaCode(fileToParse, string(content[:]))
-			d := parser.ParseDocument(s, fileToParse)
+			defer wg.Done()
+
+			for fileToParse := range files {
+				content, err := ioutil.ReadFile(fileToParse)
+				if err != nil {
+					report(fileError("read", fileToParse, err))
+					continue
+				}
+
+				if cache != nil {
+					if d, ok := cache.Load(fileToParse, content); ok {
+						documents <- d
+						continue
+					}
+				}
+
+				s := parser.BeginScanningJavaCode(fileToParse, string(content))
+				d := parser.ParseDocument(s, fileToParse)
 
-			documents <- d
-			wg.Done()
+				// Visitors change documents as they go, so they're cached
+				// before being sent on
+				if cache != nil {
+					if err := cache.Store(fileToParse, content, d); err != nil {
+						logger.Report(logger.Diagnostic{
+							Level:   logger.LOG_LEVEL_WARN,
+							Code:    "could-not-cache",
+							File:    fileToParse,
+							Message: err.Error(),
+						})
+					}
+				}
+
+				documents <- d
+			}
 		}()
-		wg.Add(1)
 	}
 
 	go func() {
@@ -61,10 +318,24 @@ func main() {
 		close(documents)
 	}()
 
-	options := parser.VisitorConfigOptions{
-		OutputDirectory: outputDirectory,
-		SkipPrivateDefs: skipPrivateDefs,
-	}
+	return documents
+}
+
+// afterSearch passes on every document, then reports each directory which
+// couldn't be searched.
+func afterSearch(ctx *util.SearchContext, documents chan *parser.Document, report func(error)) chan *parser.Document {
+	passed := make(chan *parser.Document)
+
+	go func() {
+		for d := range documents {
+			passed <- d
+		}
+
+		for _, err := range ctx.Errors {
+			report(fileError("read directory", ctx.Root, err))
+		}
+		close(passed)
+	}()
 
-	parser.VisitDocuments(&options, documents)
+	return passed
 }
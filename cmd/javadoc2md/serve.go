@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/dburkart/javadoc2md/internal/logger"
+	"github.com/dburkart/javadoc2md/internal/parser"
+	"github.com/dburkart/javadoc2md/internal/util"
+)
+
+// Added to every page, so that it reloads whenever the site is rendered again
+const reloadScript = `<script>new EventSource("/_reload").onmessage = () => location.reload();</script>
+`
+
+// A preview is the site rendered in memory, which is served until it's
+// rendered again.
+type preview struct {
+	mutex  sync.Mutex
+	files  map[string][]byte
+	err    error
+	reload chan struct{} // Closed when the site is rendered again
+}
+
+// update replaces the site with a new rendering, and tells every page
+// showing the old one to reload.
+func (p *preview) update(files map[string][]byte, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.files = files
+	p.err = err
+
+	close(p.reload)
+	p.reload = make(chan struct{})
+}
+
+func (p *preview) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.Lock()
+	files, err, reload := p.files, p.err, p.reload
+	p.mutex.Unlock()
+
+	if r.URL.Path == "/_reload" {
+		serveReload(w, r, reload)
+		return
+	}
+
+	// Until the site is rendered, or while it can't be, every page says why
+	if files == nil {
+		message, status := "Rendering…", http.StatusServiceUnavailable
+		if err != nil {
+			message, status = err.Error(), http.StatusInternalServerError
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<pre>%s</pre>\n%s", html.EscapeString(message), reloadScript)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+
+	content, found := files[name]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	if path.Ext(name) == ".html" {
+		content = bytes.Replace(content, []byte("</body>"), []byte(reloadScript+"</body>"), 1)
+	}
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(content)
+}
+
+// serveReload sends an event once the site is rendered again, for pages to
+// reload on.
+func serveReload(w http.ResponseWriter, r *http.Request, reload chan struct{}) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	select {
+	case <-reload:
+		fmt.Fprint(w, "data: reload\n\n")
+	case <-r.Context().Done():
+	}
+}
+
+// serve renders the site for the given documents in memory, and serves it
+// at address, rendering it again whenever a Java file under inputDirectory
+// changes.
+func serve(options *parser.VisitorConfigOptions, documents chan *parser.Document, inputDirectory string, jobs int, cache *parser.Cache, address string) error {
+	site := &preview{reload: make(chan struct{})}
+
+	server := &http.Server{Addr: address, Handler: site}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	// The site is rendered from scratch each time, so there's nothing to remove
+	go followChanges(documents, util.Watch(inputDirectory), jobs, cache, func(all chan *parser.Document, removed []*parser.Document) {
+		files, err := parser.RenderSite(options, all)
+		if err != nil {
+			printError(err)
+		}
+		site.update(files, err)
+	})
+
+	logger.Info("Serving a preview at http://" + address)
+	return <-errs
+}
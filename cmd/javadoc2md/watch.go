@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dburkart/javadoc2md/internal/logger"
+	"github.com/dburkart/javadoc2md/internal/parser"
+	"github.com/dburkart/javadoc2md/internal/util"
+)
+
+// watch writes the documentation for the given documents, then rewrites it
+// whenever a Java file under inputDirectory changes, until interrupted. Only
+// pages whose content changed are written, and the pages of classes which
+// are gone are removed.
+func watch(options *parser.VisitorConfigOptions, documents chan *parser.Document, inputDirectory string, jobs int, cache *parser.Cache) {
+	followChanges(documents, util.Watch(inputDirectory), jobs, cache, func(all chan *parser.Document, removed []*parser.Document) {
+		for _, d := range removed {
+			if err := parser.RemovePages(options, d); err != nil {
+				printError(err)
+			}
+		}
+
+		if err := parser.VisitDocuments(options, all); err != nil {
+			printError(err)
+		}
+	})
+}
+
+// followChanges collects the given documents and calls update with every
+// one of them, then again whenever the watcher sees a Java file change,
+// until its changes end. Only the files which changed are parsed again, but
+// update is always given every document, since a change to one class can
+// break links in another. Documents which no longer exist, because their
+// file (or directory) was removed or their class was renamed, are passed to
+// update as removed.
+func followChanges(documents chan *parser.Document, watcher *util.Watcher, jobs int, cache *parser.Cache, update func(all chan *parser.Document, removed []*parser.Document)) {
+	parsed := make(map[string]*parser.Document)
+	for d := range documents {
+		parsed[d.Address] = d
+	}
+
+	updateAll := func(removed []*parser.Document) {
+		all := make(chan *parser.Document, len(parsed))
+		for _, d := range parsed {
+			all <- d
+		}
+		close(all)
+
+		update(all, removed)
+	}
+
+	updateAll(nil)
+	logger.Info("Watching " + watcher.Root + " for changes")
+
+	for changed := range watcher.Changes {
+		var removed []*parser.Document
+		previous := make(map[string]*parser.Document)
+
+		files := make(chan string, len(changed))
+		for _, path := range changed {
+			if _, err := os.Stat(path); err != nil {
+				// The path may be a directory, which takes every file under
+				// it along with it
+				for address, d := range parsed {
+					if address == path || strings.HasPrefix(address, path+string(filepath.Separator)) {
+						removed = append(removed, d)
+						delete(parsed, address)
+					}
+				}
+				continue
+			}
+
+			if d, found := parsed[path]; found {
+				previous[path] = d
+			}
+			files <- path
+		}
+		close(files)
+
+		for d := range parseFiles(files, jobs, cache, printError) {
+			parsed[d.Address] = d
+
+			if old, found := previous[d.Address]; found && pageName(old) != pageName(d) {
+				removed = append(removed, old)
+			}
+		}
+
+		updateAll(removed)
+		logger.Info("Updated after changes to " + strings.Join(changed, ", "))
+	}
+}
+
+// pageName returns the name of the class a document's page is named after.
+func pageName(d *parser.Document) string {
+	if !d.HasClass() {
+		return ""
+	}
+	return d.Blocks[0].Name
+}
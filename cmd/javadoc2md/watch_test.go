@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/dburkart/javadoc2md/internal/parser"
+	"github.com/dburkart/javadoc2md/internal/util"
+)
+
+// An update is what followChanges passed to update: the classes of every
+// document, and of those removed.
+type update struct {
+	all     []string
+	removed []string
+}
+
+func classNames(docs []*parser.Document) []string {
+	names := []string{}
+	for _, d := range docs {
+		names = append(names, d.Blocks[0].Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeClass(t *testing.T, path string, class string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	source := "/**\n * The " + class + " class\n */\npublic class " + class + " {}\n"
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollowChanges(t *testing.T) {
+	root := t.TempDir()
+	one := filepath.Join(root, "One.java")
+	two := filepath.Join(root, "Two.java")
+	nested := filepath.Join(root, "nested")
+	three := filepath.Join(nested, "Three.java")
+	four := filepath.Join(root, "Four.java")
+
+	writeClass(t, one, "One")
+	writeClass(t, two, "Two")
+	writeClass(t, three, "Three")
+
+	files := make(chan string, 3)
+	files <- one
+	files <- two
+	files <- three
+	close(files)
+
+	watcher := &util.Watcher{Root: root, Changes: make(chan []string)}
+	updates := make(chan update)
+	done := make(chan struct{})
+
+	go func() {
+		followChanges(parseFiles(files, 1, nil, printError), watcher, 1, nil, func(all chan *parser.Document, removed []*parser.Document) {
+			var docs []*parser.Document
+			for d := range all {
+				docs = append(docs, d)
+			}
+			updates <- update{classNames(docs), classNames(removed)}
+		})
+		close(done)
+	}()
+
+	expect := func(step string, expected update) {
+		t.Helper()
+
+		if got := <-updates; !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: got %+v, wanted %+v", step, got, expected)
+		}
+	}
+
+	expect("start", update{[]string{"One", "Three", "Two"}, []string{}})
+
+	writeClass(t, four, "Four")
+	watcher.Changes <- []string{four}
+	expect("create", update{[]string{"Four", "One", "Three", "Two"}, []string{}})
+
+	writeClass(t, one, "One")
+	watcher.Changes <- []string{one}
+	expect("modify", update{[]string{"Four", "One", "Three", "Two"}, []string{}})
+
+	if err := os.Remove(two); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Changes <- []string{two}
+	expect("delete", update{[]string{"Four", "One", "Three"}, []string{"Two"}})
+
+	if err := os.RemoveAll(nested); err != nil {
+		t.Fatal(err)
+	}
+	watcher.Changes <- []string{nested}
+	expect("delete directory", update{[]string{"Four", "One"}, []string{"Three"}})
+
+	writeClass(t, one, "Renamed")
+	watcher.Changes <- []string{one}
+	expect("rename", update{[]string{"Four", "Renamed"}, []string{"One"}})
+
+	close(watcher.Changes)
+	<-done
+}
@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
@@ -16,8 +18,81 @@ const (
 	LOG_LEVEL_ERROR
 )
 
+func (l LogLevel) String() string {
+	switch l {
+	case LOG_LEVEL_DEBUG:
+		return "debug"
+	case LOG_LEVEL_INFO:
+		return "info"
+	case LOG_LEVEL_WARN:
+		return "warning"
+	}
+	return "error"
+}
+
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// The format diagnostics are written in
+type LogFormat int
+
+const (
+	LOG_FORMAT_TEXT LogFormat = iota // i.e. "Foo.java:3:5: warning: unresolved link to Bar [unresolved-link]"
+	LOG_FORMAT_JSON                  // A JSON object on each line
+)
+
+// FormatForString returns the format with the given name, "text" or "json".
+func FormatForString(s string) (LogFormat, error) {
+	switch strings.ToLower(s) {
+	case "text":
+		return LOG_FORMAT_TEXT, nil
+	case "json":
+		return LOG_FORMAT_JSON, nil
+	}
+	return LOG_FORMAT_TEXT, fmt.Errorf("unknown log format %q", s)
+}
+
 type Logger struct {
-	level LogLevel
+	level  LogLevel
+	format LogFormat
+	output io.Writer
+	mutex  sync.Mutex
+}
+
+// A Diagnostic is a message about a place in a source file, or about the run
+// as a whole if it has no File.
+type Diagnostic struct {
+	Level   LogLevel `json:"level"`
+	Code    string   `json:"code,omitempty"` // A stable name for the kind of message, i.e. "unresolved-link"
+	File    string   `json:"file,omitempty"`
+	Line    int      `json:"line,omitempty"`
+	Column  int      `json:"column,omitempty"`
+	Message string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	var s strings.Builder
+
+	if d.File != "" {
+		s.WriteString(d.File)
+		if d.Line > 0 {
+			fmt.Fprintf(&s, ":%d:%d", d.Line, d.Column)
+		}
+		s.WriteString(": ")
+	}
+
+	// Informational messages are just that, and need no label
+	if d.Level >= LOG_LEVEL_WARN {
+		s.WriteString(d.Level.String() + ": ")
+	}
+
+	s.WriteString(d.Message)
+	if d.Code != "" {
+		s.WriteString(" [" + d.Code + "]")
+	}
+
+	return s.String()
 }
 
 func LevelForString(s string) LogLevel {
@@ -26,7 +101,7 @@ func LevelForString(s string) LogLevel {
 		return LOG_LEVEL_DEBUG
 	case "info":
 		return LOG_LEVEL_INFO
-	case "warn":
+	case "warn", "warning":
 		return LOG_LEVEL_WARN
 	case "error":
 		return LOG_LEVEL_ERROR
@@ -37,43 +112,85 @@ func LevelForString(s string) LogLevel {
 var once sync.Once
 var logger *Logger
 
+// Initialize sets up logging to stderr, at the level given by the LOG_LEVEL
+// environment variable.
 func Initialize() {
-	levelString, ok := os.LookupEnv("LOG_LEVEL")
+	once.Do(func() {
+		levelString, ok := os.LookupEnv("LOG_LEVEL")
+
+		if !ok {
+			levelString = "info"
+		}
+
+		logger = &Logger{level: LevelForString(levelString), output: os.Stderr}
+	})
+}
+
+// Configure sets the level below which messages are dropped, and the format
+// the rest are written in.
+func Configure(level LogLevel, format LogFormat) {
+	Initialize()
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	logger.level = level
+	logger.format = format
+}
+
+// SetOutput sets where diagnostics are written, which is stderr by default.
+func SetOutput(w io.Writer) {
+	Initialize()
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
 
-	if !ok {
-		levelString = "info"
+	logger.output = w
+}
+
+// Level returns the level below which messages are dropped.
+func Level() LogLevel {
+	Initialize()
+	return logger.level
+}
+
+// enabled returns whether messages of the given level are logged. Logging
+// before Initialize is called (i.e. from tests) uses the default level.
+func enabled(level LogLevel) bool {
+	Initialize()
+	return logger.level <= level
+}
+
+// Report writes a diagnostic, if its level is enabled.
+func Report(d Diagnostic) {
+	if !enabled(d.Level) {
+		return
 	}
 
-	level := LevelForString(levelString)
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
 
-	if logger == nil {
-		once.Do(
-			func() {
-				logger = &Logger{level: level}
-			})
+	if logger.format == LOG_FORMAT_JSON {
+		line, _ := json.Marshal(d)
+		fmt.Fprintln(logger.output, string(line))
+		return
 	}
+
+	fmt.Fprintln(logger.output, d)
 }
 
 func Debug(s string) {
-	if logger.level <= LOG_LEVEL_DEBUG {
-		fmt.Println(s)
-	}
+	Report(Diagnostic{Level: LOG_LEVEL_DEBUG, Message: s})
 }
 
 func Info(s string) {
-	if logger.level <= LOG_LEVEL_INFO {
-		fmt.Println(s)
-	}
+	Report(Diagnostic{Level: LOG_LEVEL_INFO, Message: s})
 }
 
 func Warn(s string) {
-	if logger.level <= LOG_LEVEL_WARN {
-		fmt.Println(s)
-	}
+	Report(Diagnostic{Level: LOG_LEVEL_WARN, Message: s})
 }
 
 func Error(s string) {
-	if logger.level <= LOG_LEVEL_ERROR {
-		fmt.Println(s)
-	}
+	Report(Diagnostic{Level: LOG_LEVEL_ERROR, Message: s})
 }
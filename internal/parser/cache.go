@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// The version of the cache's format. It must be changed whenever the model
+// of a Document changes, or the parser builds a different one from the same
+// source, so that stale documents aren't used.
+const cacheVersion = 4
+
+// A Cache keeps the document parsed from each file in a directory, along
+// with the hash of the file's content, so that files which haven't changed
+// since the last run needn't be parsed again.
+type Cache struct {
+	Directory string
+}
+
+type cacheEntry struct {
+	Version  int       `json:"version"`
+	Hash     string    `json:"hash"`
+	Document *Document `json:"document"`
+}
+
+// OpenCache returns the cache kept in directory, creating the directory if
+// it doesn't exist yet.
+func OpenCache(directory string) (*Cache, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Cache{Directory: directory}, nil
+}
+
+// Load returns the document parsed from the file at path, if it's been
+// cached since the file last changed.
+func (c *Cache) Load(path string, content []byte) (*Document, bool) {
+	serialized, err := os.ReadFile(c.entryPath(path))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err = json.Unmarshal(serialized, &entry); err != nil || entry.Version != cacheVersion || entry.Hash != hash(content) {
+		return nil, false
+	}
+
+	doc := entry.Document
+	if doc == nil {
+		return nil, false
+	}
+
+	doc.Address = path
+	for i := range doc.Blocks {
+		doc.Blocks[i].Doc = doc
+	}
+
+	return doc, true
+}
+
+// Store caches the document parsed from the file at path. It must be stored
+// as parsed, before any visitor has changed it.
+func (c *Cache) Store(path string, content []byte, doc *Document) error {
+	serialized, err := json.Marshal(cacheEntry{Version: cacheVersion, Hash: hash(content), Document: doc})
+	if err != nil {
+		return err
+	}
+
+	// Write the entry in full before putting it in place, so that an
+	// interrupted run never leaves half of one behind
+	temporary, err := os.CreateTemp(c.Directory, "entry-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = temporary.Write(serialized)
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), c.entryPath(path))
+	}
+	if err != nil {
+		os.Remove(temporary.Name())
+	}
+
+	return err
+}
+
+// entryPath returns where the entry for the file at path is kept.
+func (c *Cache) entryPath(path string) string {
+	return filepath.Join(c.Directory, hash([]byte(path))+".json")
+}
+
+func hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeIfChanged writes content to the file at path, unless the file already
+// holds exactly that content. Leaving unchanged files alone preserves their
+// modification times, so that whatever builds a site from them can tell
+// which have changed.
+func writeIfChanged(path string, content []byte) error {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	content := []byte(`package com.example;
+
+import java.util.List;
+
+/**
+ * A <b>simple</b> class, see {@link List}.
+ */
+public class Simple {
+	/**
+	 * Adds two numbers together
+	 *
+	 * @param a the first number
+	 * @return the sum
+	 */
+	public int add(int a, int b);
+
+	public int undocumented;
+}`)
+
+	cache, err := OpenCache(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.Load("Simple.java", content); ok {
+		t.Errorf("found a document before one was stored")
+	}
+
+	parsed := ParseDocument(BeginScanningJavaCode("Simple.java", string(content)), "Simple.java")
+	if err = cache.Store("Simple.java", content, parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	cached, ok := cache.Load("Simple.java", content)
+	if !ok {
+		t.Fatalf("stored document wasn't found")
+	}
+
+	if !reflect.DeepEqual(cached, parsed) {
+		t.Errorf("got %+v from the cache, wanted %+v", cached, parsed)
+	}
+
+	if cached.Blocks[1].Doc != cached {
+		t.Errorf("cached blocks don't refer to their document")
+	}
+
+	if _, ok = cache.Load("Simple.java", append(content, '\n')); ok {
+		t.Errorf("found a document for changed content")
+	}
+
+	if _, ok = cache.Load("Other.java", content); ok {
+		t.Errorf("found a document for another file")
+	}
+}
+
+func TestWriteIfChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page.md")
+	if err := writeIfChanged(path, []byte("# Page\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeIfChanged(path, []byte("# Page\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, _ := os.Stat(path); !info.ModTime().Equal(past) {
+		t.Errorf("unchanged file was rewritten")
+	}
+
+	if err := writeIfChanged(path, []byte("# New Page\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, _ := os.ReadFile(path); string(content) != "# New Page\n" {
+		t.Errorf("got %q, wanted the changed content", content)
+	}
+}
@@ -0,0 +1,371 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// CoverageCounts counts how many of some kind of declaration are documented.
+type CoverageCounts struct {
+	Documented int
+	Total      int
+}
+
+func (c *CoverageCounts) add(documented bool) {
+	c.Total++
+	if documented {
+		c.Documented++
+	}
+}
+
+// Percent returns the percentage of declarations which are documented. When
+// there's nothing to document, everything is.
+func (c CoverageCounts) Percent() float64 {
+	if c.Total == 0 {
+		return 100
+	}
+	return 100 * float64(c.Documented) / float64(c.Total)
+}
+
+func (c CoverageCounts) String() string {
+	return fmt.Sprintf("%d/%d (%.1f%%)", c.Documented, c.Total, c.Percent())
+}
+
+func (c CoverageCounts) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Documented int     `json:"documented"`
+		Total      int     `json:"total"`
+		Percent    float64 `json:"percent"`
+	}{c.Documented, c.Total, c.Percent()})
+}
+
+// Coverage counts the public types, methods and fields which are documented,
+// as well as the parameters and return values of those methods.
+type Coverage struct {
+	Types   CoverageCounts `json:"types"`
+	Methods CoverageCounts `json:"methods"`
+	Fields  CoverageCounts `json:"fields"`
+	Params  CoverageCounts `json:"params"`
+	Returns CoverageCounts `json:"returns"`
+}
+
+// Overall sums the counts of every kind of declaration.
+func (c Coverage) Overall() CoverageCounts {
+	var overall CoverageCounts
+	for _, counts := range []CoverageCounts{c.Types, c.Methods, c.Fields, c.Params, c.Returns} {
+		overall.Documented += counts.Documented
+		overall.Total += counts.Total
+	}
+	return overall
+}
+
+func (c *Coverage) merge(other Coverage) {
+	c.Types.Documented += other.Types.Documented
+	c.Types.Total += other.Types.Total
+	c.Methods.Documented += other.Methods.Documented
+	c.Methods.Total += other.Methods.Total
+	c.Fields.Documented += other.Fields.Documented
+	c.Fields.Total += other.Fields.Total
+	c.Params.Documented += other.Params.Documented
+	c.Params.Total += other.Params.Total
+	c.Returns.Documented += other.Returns.Documented
+	c.Returns.Total += other.Returns.Total
+}
+
+// ClassCoverage is the coverage of a single document, named after the class
+// it declares.
+type ClassCoverage struct {
+	Name     string `json:"name"`
+	File     string `json:"file"`
+	Coverage `json:"coverage"`
+
+	// Every public declaration, in source order
+	Declarations []DeclarationCoverage `json:"-"`
+}
+
+// DeclarationCoverage describes a single public declaration.
+type DeclarationCoverage struct {
+	Line       int
+	Documented bool
+	Parts      CoverageCounts // Its parameters and return value
+}
+
+// PackageCoverage is the coverage of every class in a package.
+type PackageCoverage struct {
+	Name     string `json:"name"`
+	Coverage `json:"coverage"`
+	Classes  []ClassCoverage `json:"classes"`
+}
+
+// A CoverageReport is the coverage of every package, sorted by name.
+type CoverageReport struct {
+	Coverage `json:"coverage"`
+	Packages []PackageCoverage `json:"packages"`
+}
+
+// MeasureCoverage counts the public declarations in every document, and how
+// many of them are documented.
+func MeasureCoverage(options *VisitorConfigOptions, docs chan *Document) CoverageReport {
+	documents, _ := collectSymbols(options, docs)
+
+	coverageVisitor := CoverageVisitor{Packages: map[string]*PackageCoverage{}}
+	for _, doc := range documents {
+		if !doc.HasClass() {
+			continue
+		}
+
+		coverageVisitor.visit(doc)
+	}
+
+	return coverageVisitor.Report()
+}
+
+// The CoverageVisitor measures the coverage of each document it visits.
+type CoverageVisitor struct {
+	Packages map[string]*PackageCoverage
+}
+
+func (v *CoverageVisitor) visit(doc *Document) error {
+	class := ClassCoverage{Name: doc.Blocks[0].Name, File: doc.Address}
+
+	for i := range doc.Blocks {
+		block := &doc.Blocks[i]
+		if block.Name == "" || !isPublicAPI(doc, block) {
+			continue
+		}
+
+		declaration := DeclarationCoverage{Line: block.Declaration.Start.Line, Documented: !isUndocumented(block)}
+
+		switch {
+		case isType(block.Type):
+			class.Types.add(declaration.Documented)
+		case block.Type == SYM_TYPE_METHOD:
+			class.Methods.add(declaration.Documented)
+
+			for _, arg := range block.Arguments {
+				_, found := block.Params[arg.Name]
+				class.Params.add(found)
+				declaration.Parts.add(found)
+			}
+
+			if t := returnType(block); t != "" && t != "void" {
+				_, found := block.Tags["@return"]
+				class.Returns.add(found)
+				declaration.Parts.add(found)
+			}
+		default:
+			class.Fields.add(declaration.Documented)
+		}
+
+		class.Declarations = append(class.Declarations, declaration)
+	}
+
+	pkg, ok := v.Packages[doc.Package]
+	if !ok {
+		pkg = &PackageCoverage{Name: doc.Package}
+		v.Packages[doc.Package] = pkg
+	}
+
+	pkg.merge(class.Coverage)
+	pkg.Classes = append(pkg.Classes, class)
+
+	return nil
+}
+
+// Report returns the coverage of every package visited.
+func (v *CoverageVisitor) Report() CoverageReport {
+	var report CoverageReport
+
+	for _, pkg := range v.Packages {
+		sort.Slice(pkg.Classes, func(i, j int) bool {
+			return pkg.Classes[i].Name < pkg.Classes[j].Name
+		})
+
+		report.merge(pkg.Coverage)
+		report.Packages = append(report.Packages, *pkg)
+	}
+
+	sort.Slice(report.Packages, func(i, j int) bool {
+		return report.Packages[i].Name < report.Packages[j].Name
+	})
+
+	return report
+}
+
+// WriteCoverageText writes the report as a table, with a row for the whole
+// input, each package, and each class.
+func WriteCoverageText(w io.Writer, report CoverageReport) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tTYPES\tMETHODS\tFIELDS\tPARAMS\tRETURNS\tOVERALL")
+
+	cell := func(c CoverageCounts) string {
+		if c.Total == 0 {
+			return "-"
+		}
+		return c.String()
+	}
+
+	row := func(name string, c Coverage) {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, cell(c.Types), cell(c.Methods), cell(c.Fields), cell(c.Params), cell(c.Returns), cell(c.Overall()))
+	}
+
+	for _, pkg := range report.Packages {
+		name := pkg.Name
+		if name == "" {
+			name = "(default package)"
+		}
+		row(name, pkg.Coverage)
+
+		for _, class := range pkg.Classes {
+			row("  "+class.Name, class.Coverage)
+		}
+	}
+	row("TOTAL", report.Coverage)
+
+	return table.Flush()
+}
+
+// WriteCoverageJSON writes the report as JSON. Every count carries the number
+// documented, the total and the percentage.
+func WriteCoverageJSON(w io.Writer, report CoverageReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(struct {
+		Overall CoverageCounts `json:"overall"`
+		CoverageReport
+	}{report.Overall(), report})
+}
+
+// The Cobertura format describes line coverage, so each public declaration
+// is written as a line which is "hit" when documented. Its parameters and
+// return value are written as the conditions of a branch on that line.
+type coberturaReport struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      string             `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity string           `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity string          `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int    `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr,omitempty"`
+}
+
+// rate formats a count as a Cobertura rate, between 0 and 1.
+func rate(c CoverageCounts) string {
+	return fmt.Sprintf("%.4f", c.Percent()/100)
+}
+
+// WriteCoverageCobertura writes the report in the Cobertura XML format, which
+// most CI systems can chart.
+func WriteCoverageCobertura(w io.Writer, report CoverageReport, source string) error {
+	var lines, branches CoverageCounts
+	result := coberturaReport{Complexity: "0", Version: "javadoc2md", Timestamp: time.Now().Unix(), Sources: []string{source}}
+
+	for _, pkg := range report.Packages {
+		var pkgLines, pkgBranches CoverageCounts
+		cPkg := coberturaPackage{Name: pkg.Name, Complexity: "0"}
+
+		for _, class := range pkg.Classes {
+			var classLines, classBranches CoverageCounts
+			// Consumers find files under the source, so they're named
+			// relative to it
+			filename, err := filepath.Rel(source, class.File)
+			if err != nil {
+				filename = class.File
+			}
+
+			cClass := coberturaClass{Name: strings.TrimPrefix(pkg.Name+"."+class.Name, "."), Filename: filepath.ToSlash(filename), Complexity: "0"}
+
+			for _, declaration := range class.Declarations {
+				line := coberturaLine{Number: declaration.Line, Branch: declaration.Parts.Total > 0}
+				if declaration.Documented {
+					line.Hits = 1
+				}
+				if line.Branch {
+					line.ConditionCoverage = fmt.Sprintf("%.0f%% (%d/%d)", declaration.Parts.Percent(), declaration.Parts.Documented, declaration.Parts.Total)
+				}
+
+				classLines.add(declaration.Documented)
+				classBranches.Documented += declaration.Parts.Documented
+				classBranches.Total += declaration.Parts.Total
+				cClass.Lines = append(cClass.Lines, line)
+			}
+
+			cClass.LineRate, cClass.BranchRate = rate(classLines), rate(classBranches)
+			cPkg.Classes = append(cPkg.Classes, cClass)
+
+			pkgLines.Documented += classLines.Documented
+			pkgLines.Total += classLines.Total
+			pkgBranches.Documented += classBranches.Documented
+			pkgBranches.Total += classBranches.Total
+		}
+
+		cPkg.LineRate, cPkg.BranchRate = rate(pkgLines), rate(pkgBranches)
+		result.Packages = append(result.Packages, cPkg)
+
+		lines.Documented += pkgLines.Documented
+		lines.Total += pkgLines.Total
+		branches.Documented += pkgBranches.Documented
+		branches.Total += pkgBranches.Total
+	}
+
+	result.LineRate, result.BranchRate = rate(lines), rate(branches)
+	result.LinesCovered, result.LinesValid = lines.Documented, lines.Total
+	result.BranchesCovered, result.BranchesValid = branches.Documented, branches.Total
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
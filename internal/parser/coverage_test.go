@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func measureCoverage(t *testing.T, inputs map[string]string) CoverageReport {
+	docs := make(chan *Document, len(inputs))
+	for path, input := range inputs {
+		docs <- ParseDocument(BeginScanningJavaCode(path, input), path)
+	}
+	close(docs)
+
+	return MeasureCoverage(&VisitorConfigOptions{}, docs)
+}
+
+var coverageInput = map[string]string{
+	"com/example/Calculator.java": `package com.example;
+
+/**
+ * A calculator
+ */
+public class Calculator {
+	/**
+	 * Adds two numbers together
+	 *
+	 * @param a the first number
+	 * @return the sum
+	 */
+	public int add(int a, int b);
+
+	/**
+	 */
+	public void clear();
+
+	/**
+	 * Not part of the API, so not counted
+	 */
+	private void reset(int to);
+
+	/**
+	 * The current value
+	 */
+	public int value = 0;
+}`,
+	"com/example/Shape.java": `package com.example;
+
+/**
+ * A shape
+ */
+public interface Shape {
+	/**
+	 * Members of interfaces are implicitly public
+	 */
+	double area();
+}`,
+}
+
+func TestCoverageCounts(t *testing.T) {
+	report := measureCoverage(t, coverageInput)
+
+	if len(report.Packages) != 1 || len(report.Packages[0].Classes) != 2 {
+		t.Fatalf("got %+v, wanted one package of two classes", report.Packages)
+	}
+
+	calculator := report.Packages[0].Classes[0]
+	expected := Coverage{
+		Types:   CoverageCounts{1, 1},
+		Methods: CoverageCounts{1, 2},
+		Fields:  CoverageCounts{1, 1},
+		Params:  CoverageCounts{1, 2},
+		Returns: CoverageCounts{1, 1},
+	}
+	if calculator.Name != "Calculator" || calculator.Coverage != expected {
+		t.Errorf("got %s %+v, wanted Calculator %+v", calculator.Name, calculator.Coverage, expected)
+	}
+
+	shape := report.Packages[0].Classes[1]
+	if shape.Methods != (CoverageCounts{1, 1}) || shape.Returns != (CoverageCounts{0, 1}) {
+		t.Errorf("got %+v for Shape, wanted 1/1 methods and 0/1 returns", shape.Coverage)
+	}
+
+	if overall := report.Overall(); overall != (CoverageCounts{7, 10}) || overall.Percent() != 70 {
+		t.Errorf("got %s overall, wanted 7/10 (70.0%%)", overall)
+	}
+}
+
+func TestCoverageText(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteCoverageText(&out, measureCoverage(t, coverageInput)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines, wanted a header, package, two classes, total and a newline:\n%s", len(lines), out.String())
+	}
+
+	for i, prefix := range []string{"NAME", "com.example", "  Calculator", "  Shape", "TOTAL"} {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("got line %q, wanted it to start with %q", lines[i], prefix)
+		}
+	}
+
+	if !strings.HasSuffix(lines[4], "7/10 (70.0%)") {
+		t.Errorf("got total %q, wanted 7/10 (70.0%%)", lines[4])
+	}
+}
+
+func TestCoverageJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteCoverageJSON(&out, measureCoverage(t, coverageInput)); err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		Overall struct {
+			Documented int
+			Total      int
+			Percent    float64
+		}
+		Packages []struct {
+			Name    string
+			Classes []struct {
+				Name     string
+				Coverage map[string]struct{ Documented, Total int }
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Overall.Percent != 70 || report.Packages[0].Name != "com.example" {
+		t.Errorf("got %+v", report)
+	}
+
+	if params := report.Packages[0].Classes[0].Coverage["params"]; params.Documented != 1 || params.Total != 2 {
+		t.Errorf("got params %+v for Calculator, wanted 1/2", params)
+	}
+}
+
+func TestCoverageCobertura(t *testing.T) {
+	// Files are found under the input directory, and named relative to it
+	inputs := map[string]string{}
+	for path, input := range coverageInput {
+		inputs["src/"+path] = input
+	}
+
+	var out bytes.Buffer
+	if err := WriteCoverageCobertura(&out, measureCoverage(t, inputs), "src"); err != nil {
+		t.Fatal(err)
+	}
+
+	var report coberturaReport
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.LinesCovered != 5 || report.LinesValid != 6 || report.BranchesCovered != 2 || report.BranchesValid != 4 {
+		t.Errorf("got %d/%d lines and %d/%d branches, wanted 5/6 and 2/4",
+			report.LinesCovered, report.LinesValid, report.BranchesCovered, report.BranchesValid)
+	}
+
+	class := report.Packages[0].Classes[0]
+	if class.Name != "com.example.Calculator" || class.Filename != "com/example/Calculator.java" {
+		t.Errorf("got class %q in %q", class.Name, class.Filename)
+	}
+
+	add := class.Lines[1]
+	if add.Number != 13 || add.Hits != 1 || !add.Branch || add.ConditionCoverage != "67% (2/3)" {
+		t.Errorf("got %+v for add(int,int)", add)
+	}
+}
+
+func TestCoverageSkipsUnnamedBlocks(t *testing.T) {
+	report := measureCoverage(t, map[string]string{
+		"com/example/Broken.java": "package com.example;\n/** Never closed\npublic class Broken {}\n",
+		"com/example/Shape.java":  coverageInput["com/example/Shape.java"],
+	})
+
+	if len(report.Packages) != 1 || len(report.Packages[0].Classes) != 1 || report.Packages[0].Classes[0].Name != "Shape" {
+		t.Errorf("got %+v, wanted only Shape", report.Packages)
+	}
+}
+
+func TestCoverageVisibility(t *testing.T) {
+	report := measureCoverage(t, map[string]string{
+		"com/example/Color.java": `package com.example;
+
+/**
+ * A color
+ */
+public enum Color {
+	/** Red */
+	RED,
+	GREEN;
+
+	private int hidden;
+}`,
+		"com/example/Outer.java": `package com.example;
+
+/**
+ * The outer class
+ */
+public class Outer {
+	/**
+	 * For subclasses
+	 */
+	protected void extend();
+
+	private static class Hidden {
+		public void hiddenMethod();
+	}
+
+	/**
+	 * A nested interface
+	 */
+	public interface Listener {
+		void listen();
+	}
+
+	public void after();
+}`,
+	})
+
+	if len(report.Packages) != 1 || len(report.Packages[0].Classes) != 2 {
+		t.Fatalf("got %+v, wanted one package of two classes", report.Packages)
+	}
+
+	// Enum constants are counted like public fields
+	color := report.Packages[0].Classes[0]
+	if color.Name != "Color" || color.Fields != (CoverageCounts{1, 2}) {
+		t.Errorf("got %s %+v, wanted Color with 1/2 fields", color.Name, color.Coverage)
+	}
+
+	// Protected members and those of public nested types are counted, but
+	// nothing in a private nested type is
+	outer := report.Packages[0].Classes[1]
+	if outer.Name != "Outer" || outer.Types != (CoverageCounts{2, 2}) || outer.Methods != (CoverageCounts{1, 3}) {
+		t.Errorf("got %s %+v, wanted Outer with 2/2 types and 1/3 methods", outer.Name, outer.Coverage)
+	}
+}
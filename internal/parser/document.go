@@ -6,13 +6,115 @@
 
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // The Document struct represents a single "document" emitted by the transpiler.
 type Document struct {
-	Address string
-	Package string
-	Blocks  []Block
+	Address string   `json:"address"`
+	Package string   `json:"package"`
+	Imports []Import `json:"imports"`
+	Blocks  []Block  `json:"blocks"`
+}
+
+// HasClass returns whether the document declares a class (or other type)
+// which can be documented. A document whose first comment couldn't be tied
+// to a declaration, i.e. because it's never closed, has none.
+func (doc *Document) HasClass() bool {
+	return len(doc.Blocks) > 0 && doc.Blocks[0].Name != ""
+}
+
+// Enclosing returns the type which declares block, or nil if it's declared
+// at the top of the file.
+func (doc *Document) Enclosing(block *Block) *Block {
+	for i := range doc.Blocks {
+		if &doc.Blocks[i] == block {
+			return enclosingType(doc.Blocks[:i], block.Depth)
+		}
+	}
+
+	return nil
+}
+
+// enclosingType returns the type enclosing a declaration at the given depth
+// which follows blocks, or nil if there isn't one.
+func enclosingType(blocks []Block, depth int) *Block {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if blocks[i].Depth >= depth {
+			continue
+		}
+
+		if blocks[i].Depth == depth-1 && isType(blocks[i].Type) {
+			return &blocks[i]
+		}
+		break
+	}
+
+	return nil
+}
+
+// isPublicAPI returns whether a declaration is part of the public API:
+// public or protected (explicitly, or implicitly as in interfaces), and
+// declared in types which are as well. Lint and coverage both use it, so that
+// they agree on what should be documented.
+func isPublicAPI(doc *Document, block *Block) bool {
+	for ; block != nil; block = doc.Enclosing(block) {
+		visibility := block.Attributes["visibility"]
+		if visibility != "public" && visibility != "protected" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPrivate returns whether a declaration, or any type enclosing it, is
+// private.
+func isPrivate(doc *Document, block *Block) bool {
+	for ; block != nil; block = doc.Enclosing(block) {
+		if block.Attributes["visibility"] == "private" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutPrivate returns a copy of the document without its private members,
+// or the members of its private nested types, for output which skips private
+// definitions.
+func (doc *Document) withoutPrivate() *Document {
+	public := *doc
+	public.Blocks = nil
+
+	for i := range doc.Blocks {
+		if i > 0 && isPrivate(doc, &doc.Blocks[i]) {
+			continue
+		}
+		public.Blocks = append(public.Blocks, doc.Blocks[i])
+	}
+
+	return &public
+}
+
+// An Import is a single import declaration. Name is what's imported, i.e.
+// "java.util.List", "java.util.*", or for a static import "java.lang.Math.max".
+type Import struct {
+	Name   string `json:"name"`
+	Static bool   `json:"static"`
+}
+
+// OnDemand returns whether every class (or static member) is imported from
+// a package (or class).
+func (i Import) OnDemand() bool {
+	return strings.HasSuffix(i.Name, ".*")
+}
+
+// Owner returns the package or class which is imported from.
+func (i Import) Owner() string {
+	return i.Name[:strings.LastIndex(i.Name, ".")+1]
 }
 
 func (document *Document) AddBlock(block Block) {
@@ -27,22 +129,34 @@ func (document *Document) Printdbg() {
 }
 
 type ArgPair struct {
-	Type string
-	Name string
+	Type string `json:"type"`
+	Name string `json:"name"`
 }
 
 // A single Javadoc "block", whether for a class or a function
 type Block struct {
-	Doc           *Document
-	Name          string
-	QualifiedName string
-	Type          SymbolType
-	Arguments     []ArgPair
-	Text          Text
-	Definition    string
-	Tags          map[string]Text
-	Params        map[string]Text
-	Attributes    map[string]string
+	Doc           *Document         `json:"-"`
+	Name          string            `json:"name"`
+	QualifiedName string            `json:"qualifiedName"`
+	Type          SymbolType        `json:"type"`
+	Arguments     []ArgPair         `json:"arguments"`
+	Text          Text              `json:"text"`
+	Definition    string            `json:"definition"`
+	Tags          map[string]Text   `json:"tags"`
+	Params        map[string]Text   `json:"params"`
+	Attributes    map[string]string `json:"attributes"`
+	Depth         int               `json:"depth"`        // The number of types enclosing the declaration
+	Annotations   []string          `json:"annotations"`  // Annotations on the declaration, i.e. "@Command(name = \"foo\")"
+	Comment       Range             `json:"comment"`      // The Javadoc comment, from "/**" to "*/"
+	Declaration   Range             `json:"declaration"`  // The declaration following the comment
+	UnclosedTags  []Token           `json:"unclosedTags"` // Inline tags which are never closed, i.e. "{@code"
+	SourceURL     string            `json:"sourceURL,omitempty"`
+}
+
+// HasComment returns whether the declaration has a Javadoc comment, rather
+// than being found without one.
+func (block *Block) HasComment() bool {
+	return block.Comment.Start.Line != 0
 }
 
 func (block *Block) Printdbg() {
@@ -59,6 +173,7 @@ func MakeBlock() *Block {
 		Tags:          make(map[string]Text),
 		Params:        make(map[string]Text),
 		Attributes:    make(map[string]string),
+		Annotations:   []string{},
 	}
 
 	return b
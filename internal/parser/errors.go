@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import "strings"
+
+// A FileError is a problem with a single file: reading or parsing a source
+// file, or rendering or writing its documentation.
+type FileError struct {
+	Op   string // What was being done, i.e. "read" or "render"
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return "could not " + e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+// Code names the kind of problem for diagnostics, i.e. "could-not-read".
+func (e *FileError) Code() string {
+	return "could-not-" + strings.ReplaceAll(e.Op, " ", "-")
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// An ErrorList is every error met when carrying on past the first.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Err returns the list as an error, or nil if it's empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVisitDocumentsErrors(t *testing.T) {
+	sources := map[string]string{
+		"First.java":  "/** The first */\npublic class First {\n}",
+		"Second.java": "/** The second */\npublic class Second {\n}",
+	}
+
+	visit := func(failFast bool) error {
+		docs := make(chan *Document, len(sources))
+		for path, source := range sources {
+			docs <- ParseDocument(BeginScanningJavaCode(path, source), path)
+		}
+		close(docs)
+
+		options := VisitorConfigOptions{
+			OutputDirectory: filepath.Join(t.TempDir(), "missing"),
+			Flavor:          Flavors["docusaurus"],
+			FailFast:        failFast,
+		}
+		return VisitDocuments(&options, docs)
+	}
+
+	// Every document which can't be written is reported
+	var list ErrorList
+	if err := visit(false); !errors.As(err, &list) || len(list) != 2 {
+		t.Fatalf("got %v, wanted an error for each document", err)
+	}
+
+	var fileErr *FileError
+	if !errors.As(list[0], &fileErr) || fileErr.Path != "First.java" || !errors.Is(fileErr, os.ErrNotExist) {
+		t.Errorf("got %v, wanted an error writing First.java", list[0])
+	}
+
+	// Unless we stop at the first
+	if err := visit(true); !errors.As(err, &fileErr) || fileErr.Path != "First.java" {
+		t.Errorf("got %v, wanted only the error writing First.java", err)
+	}
+}
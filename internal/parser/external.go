@@ -0,0 +1,366 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// An ExternalLibrary is Javadoc published elsewhere, such as the JDK's, which
+// links can resolve to. It's the equivalent of javadoc's -linkoffline option.
+type ExternalLibrary struct {
+	URL string
+
+	// Modular is set when the library was described by an element-list, which
+	// is written by javadoc 10 and later. This also decides the anchor syntax.
+	Modular bool
+
+	// Packages maps each package in the library to its module, if any
+	Packages map[string]string
+}
+
+// LoadExternalLibrary reads the element-list (or, for older releases, the
+// package-list) at path, which may also be the directory containing it. The
+// library's documentation lives under url.
+func LoadExternalLibrary(url string, path string) (*ExternalLibrary, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		for _, name := range []string{"element-list", "package-list"} {
+			if _, err = os.Stat(filepath.Join(path, name)); err == nil {
+				path = filepath.Join(path, name)
+				break
+			}
+		}
+
+		if err != nil {
+			return nil, errors.New("no element-list or package-list found in " + path)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	library := &ExternalLibrary{
+		URL:      strings.TrimSuffix(url, "/"),
+		Modular:  filepath.Base(path) == "element-list",
+		Packages: make(map[string]string),
+	}
+
+	module := ""
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+
+		if strings.HasPrefix(line, "module:") {
+			module = strings.TrimPrefix(line, "module:")
+		} else if line != "" {
+			library.Packages[line] = module
+		}
+	}
+
+	return library, lines.Err()
+}
+
+// Every public type in java.lang (as of Java 21, along with those since
+// removed), which may be referred to by its simple name. Libraries only list
+// their packages, so this is how a class is known to be in java.lang rather
+// than in a package imported on demand.
+var javaLangClasses = map[string]bool{
+	"AbstractMethodError": true, "Appendable": true,
+	"ArithmeticException": true, "ArrayIndexOutOfBoundsException": true,
+	"ArrayStoreException": true, "AssertionError": true,
+	"AutoCloseable": true, "Boolean": true, "BootstrapMethodError": true,
+	"Byte": true, "CharSequence": true, "Character": true, "Class": true,
+	"ClassCastException": true, "ClassCircularityError": true,
+	"ClassFormatError": true, "ClassLoader": true,
+	"ClassNotFoundException": true, "ClassValue": true,
+	"CloneNotSupportedException": true, "Cloneable": true,
+	"Comparable": true, "Compiler": true, "Deprecated": true, "Double": true,
+	"Enum": true, "EnumConstantNotPresentException": true, "Error": true,
+	"Exception": true, "ExceptionInInitializerError": true, "Float": true,
+	"FunctionalInterface": true, "IllegalAccessError": true,
+	"IllegalAccessException": true, "IllegalArgumentException": true,
+	"IllegalCallerException": true, "IllegalMonitorStateException": true,
+	"IllegalStateException": true, "IllegalThreadStateException": true,
+	"IncompatibleClassChangeError": true, "IndexOutOfBoundsException": true,
+	"InheritableThreadLocal": true, "InstantiationError": true,
+	"InstantiationException": true, "Integer": true, "InternalError": true,
+	"InterruptedException": true, "Iterable": true,
+	"LayerInstantiationException": true, "LinkageError": true, "Long": true,
+	"MatchException": true, "Math": true, "Module": true,
+	"ModuleLayer": true, "NegativeArraySizeException": true,
+	"NoClassDefFoundError": true, "NoSuchFieldError": true,
+	"NoSuchFieldException": true, "NoSuchMethodError": true,
+	"NoSuchMethodException": true, "NullPointerException": true,
+	"Number": true, "NumberFormatException": true, "Object": true,
+	"OutOfMemoryError": true, "Override": true, "Package": true,
+	"Process": true, "ProcessBuilder": true, "ProcessHandle": true,
+	"Readable": true, "Record": true, "ReflectiveOperationException": true,
+	"Runnable": true, "Runtime": true, "RuntimeException": true,
+	"RuntimePermission": true, "SafeVarargs": true,
+	"SecurityException": true, "SecurityManager": true, "Short": true,
+	"StackOverflowError": true, "StackTraceElement": true,
+	"StackWalker": true, "StrictMath": true, "String": true,
+	"StringBuffer": true, "StringBuilder": true,
+	"StringIndexOutOfBoundsException": true, "SuppressWarnings": true,
+	"System": true, "Thread": true, "ThreadDeath": true, "ThreadGroup": true,
+	"ThreadLocal": true, "Throwable": true, "TypeNotPresentException": true,
+	"UnknownError": true, "UnsatisfiedLinkError": true,
+	"UnsupportedClassVersionError":  true,
+	"UnsupportedOperationException": true, "VerifyError": true,
+	"VirtualMachineError": true, "Void": true, "WrongThreadException": true,
+}
+
+var primitiveTypes = map[string]bool{
+	"boolean": true, "byte": true, "char": true, "double": true,
+	"float": true, "int": true, "long": true, "short": true,
+}
+
+// Resolve returns the symbol documented by this library which is referred to
+// by a link target, i.e. "java.util.List" or "String#format(String, Object...)".
+// The returned symbol's URL is set to the absolute URL of its documentation.
+func (l *ExternalLibrary) Resolve(target string) (symbol Symbol, found bool) {
+	class, member, isMember := strings.Cut(target, "#")
+
+	pkg, classPath := l.splitClass(class)
+	if pkg == "" {
+		return
+	}
+
+	url := l.URL + "/"
+	if module := l.Packages[pkg]; l.Modular && module != "" {
+		url += module + "/"
+	}
+	url += strings.ReplaceAll(pkg, ".", "/") + "/" + classPath + ".html"
+
+	symbol = Symbol{Type: SYM_TYPE_CLASS, Name: classPath, QualifiedName: classPath, Package: pkg, URL: url}
+
+	if isMember {
+		name, _, _ := strings.Cut(member, "(")
+
+		symbol.Type = SYM_TYPE_FIELD
+		if strings.Contains(member, "(") {
+			symbol.Type = SYM_TYPE_METHOD
+		}
+
+		symbol.Name = name
+		symbol.Parent = classPath
+		symbol.QualifiedName = member
+		symbol.URL += "#" + l.anchor(pkg, classPath, member)
+	}
+
+	return symbol, true
+}
+
+// splitClass splits a (possibly qualified) class name into the package it
+// belongs to in this library, and its name within that package.
+func (l *ExternalLibrary) splitClass(class string) (pkg string, classPath string) {
+	// The longest matching package wins, so that nested classes (i.e.
+	// "java.util.Map.Entry") are found too
+	for i := strings.LastIndex(class, "."); i > 0; i = strings.LastIndex(class[:i], ".") {
+		if _, found := l.Packages[class[:i]]; found {
+			return class[:i], class[i+1:]
+		}
+	}
+
+	if _, found := l.Packages["java.lang"]; found && javaLangClasses[class] {
+		return "java.lang", class
+	}
+
+	return "", ""
+}
+
+// anchor returns the anchor of a member of a class in this library. Methods
+// are written with qualified parameter types, in the syntax used by the
+// javadoc which wrote the library: "format(java.lang.String,java.lang.Object...)"
+// from JDK 10 on, and "format-java.lang.String-java.lang.Object...-" before.
+func (l *ExternalLibrary) anchor(pkg string, classPath string, member string) string {
+	name, params, isMethod := strings.Cut(member, "(")
+	if !isMethod {
+		return member
+	}
+
+	var types []string
+	for _, param := range splitParameters(strings.TrimSuffix(params, ")")) {
+		if param = strings.TrimSpace(param); param != "" {
+			types = append(types, l.qualifyType(pkg, param))
+		}
+	}
+
+	// Constructors are named after the class, or "<init>" from JDK 10 on
+	isConstructor := name == classPath[strings.LastIndex(classPath, ".")+1:]
+
+	if l.Modular {
+		if isConstructor {
+			name = "%3Cinit%3E"
+		}
+		return name + "(" + strings.Join(types, ",") + ")"
+	}
+
+	for i := range types {
+		types[i] = strings.ReplaceAll(types[i], "[]", ":A")
+	}
+	return name + "-" + strings.Join(types, "-") + "-"
+}
+
+// splitParameters splits a parameter list on the commas which aren't part of
+// type arguments, i.e. "Map<K,V>,int" is two parameters.
+func splitParameters(params string) []string {
+	var result []string
+	depth, start := 0, 0
+
+	for i, c := range params {
+		switch c {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				result = append(result, params[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(result, params[start:])
+}
+
+// qualifyType returns the erasure of a parameter type, qualified the way
+// javadoc writes it in anchors. Any simple name which wasn't imported, and
+// isn't in java.lang, is assumed to be in the class's own package.
+func (l *ExternalLibrary) qualifyType(pkg string, param string) string {
+	// Drop any type arguments, then the parameter's name, if given
+	param = erasure(param)
+	if fields := strings.Fields(param); len(fields) > 1 {
+		param = fields[0]
+	}
+
+	name := strings.TrimRight(param, "[].")
+	suffix := param[len(name):]
+
+	switch {
+	case primitiveTypes[name], strings.Contains(name, "."), isTypeVariable(name):
+	case javaLangClasses[name]:
+		name = "java.lang." + name
+	default:
+		name = pkg + "." + name
+	}
+
+	return name + suffix
+}
+
+// isTypeVariable guesses whether a name is a type variable, i.e. the E in
+// List<E>, which javadoc writes as-is.
+func isTypeVariable(name string) bool {
+	return len(name) <= 2 && strings.ToUpper(name) == name
+}
+
+// The ExternalLinkVisitor adds a symbol for each link which can't be resolved
+// within the input, but can be by one of the external libraries. It must run
+// after the SymbolVisitor.
+type ExternalLinkVisitor struct {
+	Libraries []*ExternalLibrary
+	Symbols   SymbolMap
+}
+
+func (v *ExternalLinkVisitor) visit(doc *Document) error {
+	for _, link := range links(doc) {
+		target := linkTarget(doc, link.Lexeme)
+
+		var linkErr *LinkError
+		if _, err := v.Symbols.Resolve(doc, target); errors.As(err, &linkErr) && !linkErr.Ambiguous() {
+			v.resolve(doc, target)
+		}
+	}
+
+	return nil
+}
+
+// resolve adds a symbol for target under the first of its candidate names
+// which one of the libraries documents.
+func (v *ExternalLinkVisitor) resolve(doc *Document, target string) {
+	for _, name := range append(candidates(doc, target), target) {
+		for _, library := range v.Libraries {
+			if symbol, found := library.Resolve(qualifyParameters(doc, name)); found {
+				v.Symbols.Add(name, symbol)
+				return
+			}
+		}
+	}
+}
+
+// qualifyParameters qualifies the parameter types of a method reference
+// which were imported by doc, since they can't be found in the library.
+func qualifyParameters(doc *Document, target string) string {
+	name, params, isMethod := strings.Cut(target, "(")
+	if !isMethod {
+		return target
+	}
+
+	var qualified []string
+	for _, param := range splitParameters(strings.TrimSuffix(params, ")")) {
+		param = strings.TrimSpace(param)
+
+		for _, imp := range doc.Imports {
+			if !imp.Static && !imp.OnDemand() && strings.HasPrefix(param, imp.Name[len(imp.Owner()):]) {
+				rest := param[len(imp.Name)-len(imp.Owner()):]
+				if rest == "" || !isIdentifierRune(rune(rest[0])) {
+					param = imp.Name + rest
+					break
+				}
+			}
+		}
+
+		qualified = append(qualified, param)
+	}
+
+	return name + "(" + strings.Join(qualified, ",") + ")"
+}
+
+// ExternalLibraryFlag collects the libraries named by a repeatable command
+// line flag, each given as "URL=PATH".
+type ExternalLibraryFlag []*ExternalLibrary
+
+func (f *ExternalLibraryFlag) String() string {
+	var urls []string
+	for _, library := range *f {
+		urls = append(urls, library.URL)
+	}
+	return strings.Join(urls, ",")
+}
+
+func (f *ExternalLibraryFlag) Set(value string) error {
+	// URLs may have an "=" in their query string, but paths seldom do
+	i := strings.LastIndex(value, "=")
+	if i < 0 {
+		return errors.New("expected URL=PATH, where PATH is an element-list or package-list")
+	}
+	url, path := value[:i], value[i+1:]
+
+	library, err := LoadExternalLibrary(url, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return errors.New("no such file: " + path)
+	} else if err != nil {
+		return err
+	}
+
+	*f = append(*f, library)
+	return nil
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadLibrary(t *testing.T, url string, file string, content string) *ExternalLibrary {
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	library, err := LoadExternalLibrary(url, directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return library
+}
+
+func TestExternalLibraryResolve(t *testing.T) {
+	modular := loadLibrary(t, "https://docs.oracle.com/en/java/javase/17/docs/api/", "element-list",
+		"module:java.base\njava.lang\njava.util\nmodule:java.sql\njava.sql\n")
+	legacy := loadLibrary(t, "https://docs.oracle.com/javase/8/docs/api", "package-list",
+		"java.lang\njava.util\n")
+
+	tests := []struct {
+		library  *ExternalLibrary
+		target   string
+		expected string
+	}{
+		{modular, "java.util.List", "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/util/List.html"},
+		{modular, "java.sql.Connection", "https://docs.oracle.com/en/java/javase/17/docs/api/java.sql/java/sql/Connection.html"},
+		{modular, "java.util.Map.Entry", "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/util/Map.Entry.html"},
+		{modular, "String#format(String,Object...)", "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/lang/String.html#format(java.lang.String,java.lang.Object...)"},
+		{modular, "java.util.Map#putAll(Map<? extends K,? extends V>)", "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/util/Map.html#putAll(java.util.Map)"},
+		{modular, "java.util.List#add(E)", "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/util/List.html#add(E)"},
+		{modular, "java.util.ArrayList#ArrayList(Collection<? extends E>)", "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/util/ArrayList.html#%3Cinit%3E(java.util.Collection)"},
+		{modular, "Integer#MAX_VALUE", "https://docs.oracle.com/en/java/javase/17/docs/api/java.base/java/lang/Integer.html#MAX_VALUE"},
+		{legacy, "String#format(String,Object...)", "https://docs.oracle.com/javase/8/docs/api/java/lang/String.html#format-java.lang.String-java.lang.Object...-"},
+		{legacy, "java.util.Arrays#sort(int[])", "https://docs.oracle.com/javase/8/docs/api/java/util/Arrays.html#sort-int:A-"},
+		{legacy, "Object#toString()", "https://docs.oracle.com/javase/8/docs/api/java/lang/Object.html#toString--"},
+	}
+
+	for _, test := range tests {
+		symbol, found := test.library.Resolve(test.target)
+		if !found {
+			t.Errorf("%s: not found", test.target)
+		} else if symbol.URL != test.expected {
+			t.Errorf("%s: got %s, wanted %s", test.target, symbol.URL, test.expected)
+		}
+	}
+
+	for _, target := range []string{"com.example.Widget", "Widget", "java.util"} {
+		if _, found := modular.Resolve(target); found {
+			t.Errorf("%s: expected not to resolve", target)
+		}
+	}
+}
+
+func TestExternalLinks(t *testing.T) {
+	input := `
+import java.util.concurrent.TimeUnit;
+
+/**
+ * Wraps a {@link java.util.List}, formatted with {@link String#format(String, Object...)}.
+ * Waits in {@link TimeUnit#sleep(long)}.
+ */
+public class Wrapper {
+}`
+	library := loadLibrary(t, "https://example.com/api", "element-list", "module:java.base\njava.lang\njava.util\njava.util.concurrent\n")
+
+	s := BeginScanningJavaCode("Test", input)
+	d := ParseDocument(s, "Test.java")
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(d)
+	externalVisitor := ExternalLinkVisitor{Libraries: []*ExternalLibrary{library}, Symbols: symbolVisitor.Symbols}
+	externalVisitor.visit(d)
+
+	text := d.Blocks[0].Text.Interpolate(d, symbolVisitor.Symbols, Flavors["docusaurus"], "")
+	expected := "Wraps a [`List`](https://example.com/api/java.base/java/util/List.html), formatted with " +
+		"[`String.format(String, Object...)`](https://example.com/api/java.base/java/lang/String.html#format(java.lang.String,java.lang.Object...)).\n" +
+		"Waits in [`TimeUnit.sleep(long)`](https://example.com/api/java.base/java/util/concurrent/TimeUnit.html#sleep(long))."
+	if strings.TrimSpace(text) != expected {
+		t.Errorf("got %q, wanted %q", text, expected)
+	}
+
+	// AsciiDoc cross-references only reach pages within the site
+	text = d.Blocks[0].Text.Interpolate(d, symbolVisitor.Symbols, Flavors["asciidoc"], "")
+	expected = "Wraps a link:https://example.com/api/java.base/java/util/List.html[`+List+`], formatted with " +
+		"link:https://example.com/api/java.base/java/lang/String.html#format(java.lang.String,java.lang.Object...)[`+String.format(String, Object...)+`].\n" +
+		"Waits in link:https://example.com/api/java.base/java/util/concurrent/TimeUnit.html#sleep(long)[`+TimeUnit.sleep(long)+`]."
+	if strings.TrimSpace(text) != expected {
+		t.Errorf("got %q, wanted %q", text, expected)
+	}
+
+	local := (AsciiDocSyntax{}).Link("Wrapper", "Wrapper.adoc")
+	if local != "xref:Wrapper.adoc[Wrapper]" {
+		t.Errorf("got %q for a local page, wanted an xref", local)
+	}
+}
+
+func TestExternalLinksWithWildcardImport(t *testing.T) {
+	input := `
+import java.util.*;
+
+/**
+ * Formats a {@link List} with {@link String#format(String, Object...)}.
+ *
+ * @throws InterruptedException see {@link InterruptedException}
+ */
+public class Formatter {
+}`
+	library := loadLibrary(t, "https://example.com/api", "element-list", "module:java.base\njava.lang\njava.util\n")
+
+	d := ParseDocument(BeginScanningJavaCode("Test", input), "Test.java")
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(d)
+	externalVisitor := ExternalLinkVisitor{Libraries: []*ExternalLibrary{library}, Symbols: symbolVisitor.Symbols}
+	externalVisitor.visit(d)
+
+	text := d.Blocks[0].Text.Interpolate(d, symbolVisitor.Symbols, Flavors["docusaurus"], "")
+	expected := "Formats a [`List`](https://example.com/api/java.base/java/util/List.html) with " +
+		"[`String.format(String, Object...)`](https://example.com/api/java.base/java/lang/String.html#format(java.lang.String,java.lang.Object...))."
+	if strings.TrimSpace(text) != expected {
+		t.Errorf("got %q, wanted %q", text, expected)
+	}
+
+	tag := d.Blocks[0].Tags["@throws"]
+	throws := tag.Interpolate(d, symbolVisitor.Symbols, Flavors["docusaurus"], "")
+	expected = "InterruptedException see [`InterruptedException`](https://example.com/api/java.base/java/lang/InterruptedException.html)"
+	if strings.TrimSpace(throws) != expected {
+		t.Errorf("got %q, wanted %q", throws, expected)
+	}
+}
+
+func TestExternalLibraryFlag(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "element-list")
+	if err := os.WriteFile(path, []byte("module:java.base\njava.lang\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var libraries ExternalLibraryFlag
+	if err := libraries.Set("https://example.com/api?version=17=" + path); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(libraries) != 1 || libraries[0].URL != "https://example.com/api?version=17" {
+		t.Errorf("got %s, wanted https://example.com/api?version=17", libraries.String())
+	}
+
+	if err := libraries.Set("https://example.com/api"); err == nil {
+		t.Error("expected an error without a path")
+	}
+}
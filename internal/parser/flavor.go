@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Flavor describes the dialect of markup expected by a particular site
+// generator. Most of the differences live in the flavor's built-in templates,
+// found in templates/<Templates>.
+type Flavor struct {
+	Name      string
+	Format    string // The output format, i.e. "markdown", "asciidoc", "html", "man" or "json"
+	Templates string
+
+	// SharedTemplates names the directory of templates shared with similar
+	// flavors, i.e. the class page of every markdown flavor. Any template
+	// found in templates/<Templates> replaces the shared one of the same name.
+	SharedTemplates string
+
+	// Extension is appended to the class name to form each page's file name
+	Extension string
+
+	// LinkExtension is appended to the page name in links between pages,
+	// since some generators resolve links to source files rather than routes
+	LinkExtension string
+
+	// Extras lists additional files written once per run, mapped to the name
+	// of the template (handed an IndexData) which renders them
+	Extras map[string]string
+
+	// MDX is set for flavors whose pages are compiled as MDX, which requires
+	// text to be escaped and every JSX tag to be balanced
+	MDX bool
+
+	// SafeAnchors is set for flavors which only accept letters, digits and
+	// a little punctuation in anchors, so "add(int,int)" becomes "add-int-int-"
+	SafeAnchors bool
+
+	Syntax Syntax
+}
+
+var Flavors = map[string]*Flavor{
+	"docusaurus": {
+		Name:            "docusaurus",
+		Format:          "markdown",
+		Templates:       "docusaurus",
+		SharedTemplates: "markdown",
+		Extension:       ".md",
+		Syntax:          MarkdownSyntax{},
+	},
+	"mdx": {
+		Name:            "mdx",
+		Format:          "markdown",
+		Templates:       "docusaurus",
+		SharedTemplates: "markdown",
+		Extension:       ".mdx",
+		MDX:             true,
+		Syntax:          MarkdownSyntax{},
+	},
+	"mkdocs": {
+		Name:            "mkdocs",
+		Format:          "markdown",
+		Templates:       "mkdocs",
+		SharedTemplates: "markdown",
+		Extension:       ".md",
+		LinkExtension:   ".md",
+		Extras:          map[string]string{"mkdocs-nav.yml": "nav"},
+		Syntax:          MarkdownSyntax{},
+	},
+	"gfm": {
+		Name:            "gfm",
+		Format:          "markdown",
+		Templates:       "gfm",
+		SharedTemplates: "markdown",
+		Extension:       ".md",
+		LinkExtension:   ".md",
+		Syntax:          MarkdownSyntax{},
+	},
+	"asciidoc": {
+		Name:          "asciidoc",
+		Format:        "asciidoc",
+		Templates:     "asciidoc",
+		Extension:     ".adoc",
+		LinkExtension: ".adoc",
+		Extras:        map[string]string{"nav.adoc": "nav"},
+		SafeAnchors:   true,
+		Syntax:        AsciiDocSyntax{},
+	},
+	"html": {
+		Name:          "html",
+		Format:        "html",
+		Templates:     "html",
+		Extension:     ".html",
+		LinkExtension: ".html",
+		Syntax:        HTMLSyntax{},
+	},
+	"man": {
+		Name:      "man",
+		Format:    "man",
+		Templates: "man",
+		Syntax:    RoffSyntax{},
+	},
+	// The JSON model isn't rendered with templates, or any syntax
+	"json": {
+		Name:      "json",
+		Format:    "json",
+		Extension: ".json",
+	},
+}
+
+// FindFlavor returns the flavor of the given output format. Only markdown
+// comes in more than one flavor, so name is ignored for any other format.
+func FindFlavor(format string, name string) (*Flavor, error) {
+	if format == "markdown" {
+		if flavor, ok := Flavors[name]; ok && flavor.Format == format {
+			return flavor, nil
+		}
+
+		return nil, fmt.Errorf("unknown flavor %q (expected one of %s)", name, strings.Join(flavorNames(format), ", "))
+	}
+
+	for _, flavor := range Flavors {
+		if flavor.Format == format {
+			return flavor, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func flavorNames(format string) []string {
+	var names []string
+	for k, flavor := range Flavors {
+		if flavor.Format == format {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Link turns a symbol's location ("Page" or "Page#anchor") into a link
+// target for this flavor.
+func (f *Flavor) Link(location string) string {
+	page, anchor, hasAnchor := strings.Cut(location, "#")
+
+	link := page + f.LinkExtension
+	if hasAnchor {
+		link += "#" + f.Anchor(anchor)
+	}
+
+	return link
+}
+
+var unsafeAnchorCharacters = strings.NewReplacer("(", "-", ")", "-", ",", "-", " ", "", "<", "-", ">", "-", "[", ":A", "]", "")
+
+// Anchor returns the anchor used for a member with the given qualified name.
+func (f *Flavor) Anchor(name string) string {
+	if !f.SafeAnchors {
+		return name
+	}
+
+	return unsafeAnchorCharacters.Replace(name)
+}
@@ -0,0 +1,243 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LoadHTMLTemplates builds the template set used to render the static site.
+// It works just like LoadTemplates, except that templates are parsed as
+// html/template templates so that everything but interpolated Javadoc is
+// escaped.
+func LoadHTMLTemplates(flavor *Flavor, directory string) (*template.Template, error) {
+	t := template.New("").Funcs(template.FuncMap{
+		"anchor":      flavor.Anchor,
+		"packageLink": packageLink,
+		// raw marks interpolated Javadoc as safe, since it is HTML already
+		"raw": func(s string) template.HTML {
+			return template.HTML(s)
+		},
+	})
+
+	builtin, err := fs.Sub(builtinTemplates, "templates/"+flavor.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = parseHTMLTemplates(t, builtin); err != nil {
+		return nil, err
+	}
+
+	if directory == "" {
+		return t, nil
+	}
+
+	if err = parseHTMLTemplates(t, os.DirFS(directory)); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func parseHTMLTemplates(t *template.Template, fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		name, _, _ := strings.Cut(file, ".")
+		if _, err = t.New(name).Parse(string(content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// packageLink returns the page listing every class in a package.
+func packageLink(name string) string {
+	if name == "" {
+		name = "default"
+	}
+
+	return "package-" + name + ".html"
+}
+
+// The HTMLVisitor is responsible for emitting a self-contained static site:
+// a page for each Document, a page for each package, an index of packages,
+// and the stylesheet and search index used by every page.
+type HTMLVisitor struct {
+	OutputDirectory  string
+	SkipPrivateDefs  bool
+	FlagUndocumented bool
+	Symbols          SymbolMap
+	Templates        *template.Template
+	Flavor           *Flavor
+
+	// If Files is set, the site is rendered into it, keyed by file name,
+	// rather than written to OutputDirectory
+	Files map[string][]byte
+
+	// Findings to show on the page for each document, keyed by its address
+	Findings map[string][]Finding
+}
+
+func (h *HTMLVisitor) visit(doc *Document) error {
+	if h.SkipPrivateDefs {
+		if doc.Blocks[0].Attributes["visibility"] == "private" {
+			return nil
+		}
+		doc = doc.withoutPrivate()
+	}
+
+	data := makePageData(doc, h.Symbols, h.Flavor)
+	if h.FlagUndocumented {
+		data.flagUndocumented()
+	}
+	data.Findings = h.Findings[doc.Address]
+
+	return h.writeTemplate(doc.Blocks[0].Name+h.Flavor.Extension, "class", data)
+}
+
+func (h *HTMLVisitor) finish(docs []*Document) error {
+	index := makeIndexData(docs, h.Symbols, h.Flavor, h.SkipPrivateDefs)
+
+	// Summaries are shown as plain text, since cutting them at the end of the
+	// first sentence may leave tags unbalanced
+	for i := range index.Packages {
+		for j := range index.Packages[i].Pages {
+			index.Packages[i].Pages[j].Summary = plainText(index.Packages[i].Pages[j].Summary)
+		}
+	}
+
+	if err := h.writeTemplate("index.html", "index", index); err != nil {
+		return err
+	}
+
+	for _, p := range index.Packages {
+		if err := h.writeTemplate(packageLink(p.Name), "package", p); err != nil {
+			return err
+		}
+	}
+
+	assets, err := fs.Sub(builtinTemplates, "templates/"+h.Flavor.Templates+"/assets")
+	if err != nil {
+		return err
+	}
+
+	for _, asset := range []string{"style.css", "search.js"} {
+		content, err := fs.ReadFile(assets, asset)
+		if err != nil {
+			return err
+		}
+
+		if err = h.write(asset, content); err != nil {
+			return err
+		}
+	}
+
+	return h.writeSearchIndex(docs)
+}
+
+func (h *HTMLVisitor) writeTemplate(file string, name string, data any) error {
+	var page bytes.Buffer
+	if err := h.Templates.ExecuteTemplate(&page, name, data); err != nil {
+		return err
+	}
+
+	return h.write(file, page.Bytes())
+}
+
+func (h *HTMLVisitor) write(file string, content []byte) error {
+	if h.Files != nil {
+		h.Files[file] = content
+		return nil
+	}
+
+	return writeIfChanged(filepath.Join(h.OutputDirectory, file), content)
+}
+
+// A searchEntry is a single entry in the client-side search index.
+type searchEntry struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Parent  string `json:"parent,omitempty"`
+	Package string `json:"package,omitempty"`
+	URL     string `json:"url"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// writeSearchIndex writes the search index as a script assigning it to
+// window.searchIndex, rather than as a bare JSON file, so that search works
+// when the site is opened straight from disk.
+func (h *HTMLVisitor) writeSearchIndex(docs []*Document) error {
+	entries := []searchEntry{}
+
+	for _, doc := range docs {
+		if !doc.HasClass() {
+			continue
+		}
+
+		if h.SkipPrivateDefs {
+			if doc.Blocks[0].Attributes["visibility"] == "private" {
+				continue
+			}
+			doc = doc.withoutPrivate()
+		}
+
+		class := &doc.Blocks[0]
+
+		for i := range doc.Blocks {
+			block := &doc.Blocks[i]
+			entry := searchEntry{
+				Name:    block.Name,
+				Kind:    block.Type.String(),
+				Package: doc.Package,
+				URL:     h.Flavor.Link(class.Name),
+				Summary: firstSentence(plainText(block.Text.Interpolate(doc, h.Symbols, h.Flavor, ""))),
+			}
+
+			if i > 0 {
+				entry.Parent = class.Name
+				entry.URL = h.Flavor.Link(class.Name + "#" + block.QualifiedName)
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	index, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	script := "window.searchIndex = " + string(index) + ";\n"
+	return h.write("search-index.js", []byte(script))
+}
+
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// plainText strips the tags from a fragment of HTML, and collapses whitespace.
+func plainText(fragment string) string {
+	text := html.UnescapeString(htmlTags.ReplaceAllString(fragment, ""))
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLSite(t *testing.T) {
+	input := `
+package com.example;
+
+/**
+ * A class with {@code List<String>} in it. And a second sentence.
+ */
+public class Lists {
+	/**
+	 * Returns the list.
+	 *
+	 * @return a {@link Lists}
+	 */
+	public List<String> get();
+}`
+	directory := t.TempDir()
+
+	docs := make(chan *Document, 1)
+	docs <- ParseDocument(BeginScanningJavaCode("Lists.java", input), "Lists.java")
+	close(docs)
+
+	options := VisitorConfigOptions{OutputDirectory: directory, Flavor: Flavors["html"]}
+	if err := VisitDocuments(&options, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"index.html", "package-com.example.html", "Lists.html", "style.css", "search.js", "search-index.js"} {
+		if _, err := os.Stat(filepath.Join(directory, file)); err != nil {
+			t.Errorf("expected %s to be written: %s", file, err)
+		}
+	}
+
+	page, _ := os.ReadFile(filepath.Join(directory, "Lists.html"))
+	for _, expected := range []string{
+		`<div class="description">A class with <code>List&lt;String&gt;</code> in it. And a second sentence.</div>`,
+		`<h3><code>public List&lt;String&gt; get()</code></h3>`,
+		`<p>a <a href="Lists.html"><code>Lists</code></a></p>`,
+	} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("expected Lists.html to contain %q", expected)
+		}
+	}
+
+	index, _ := os.ReadFile(filepath.Join(directory, "search-index.js"))
+	expected := `window.searchIndex = [{"name":"Lists","kind":"class","package":"com.example","url":"Lists.html","summary":"A class with List\u003cString\u003e in it."},` +
+		`{"name":"get","kind":"method","parent":"Lists","package":"com.example","url":"Lists.html#get()","summary":"Returns the list."}];` + "\n"
+	if string(index) != expected {
+		t.Errorf("got %q, wanted %q", index, expected)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
+// The JSONVisitor writes the model of each document, as parsed, to a JSON
+// file: every block, its text as a list of tokens, and the range of source
+// each came from. This is useful for tools which want to do their own
+// rendering, or to point back at the source.
+type JSONVisitor struct {
+	OutputDirectory string
+	SkipPrivateDefs bool
+}
+
+func (j *JSONVisitor) visit(doc *Document) error {
+	if j.SkipPrivateDefs {
+		if doc.Blocks[0].Attributes["visibility"] == "private" {
+			return nil
+		}
+		doc = doc.withoutPrivate()
+	}
+
+	model, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fileName := doc.Blocks[0].Name + ".json"
+	return writeIfChanged(filepath.Join(j.OutputDirectory, fileName), append(model, '\n'))
+}
+
+func (j *JSONVisitor) finish(docs []*Document) error {
+	return nil
+}
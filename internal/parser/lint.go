@@ -0,0 +1,337 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Finding is a single problem with the documentation, found by the
+// LintVisitor.
+type Finding struct {
+	File   string
+	Line   int
+	Column int
+
+	// The end of the source the problem was found in, which isn't included,
+	// i.e. the column following a link
+	EndLine   int
+	EndColumn int
+
+	Rule    string // A short name for the kind of problem, i.e. "unresolved-link"
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s [%s]", f.File, f.Line, f.Column, f.Message, f.Rule)
+}
+
+// LintDocuments checks every document for problems, without writing any
+// output. Findings are returned in order of file and line.
+func LintDocuments(options *VisitorConfigOptions, docs chan *Document) []Finding {
+	documents, symbols := collectSymbols(options, docs)
+	return lintDocuments(options, documents, symbols)
+}
+
+// lintDocuments checks documents whose symbols have already been collected.
+func lintDocuments(options *VisitorConfigOptions, documents []*Document, symbols SymbolMap) []Finding {
+	lintVisitor := LintVisitor{Symbols: symbols, SkipPrivateDefs: options.SkipPrivateDefs}
+	for _, doc := range documents {
+		if !doc.HasClass() {
+			continue
+		}
+
+		lintVisitor.visit(doc)
+	}
+
+	sortFindings(lintVisitor.Findings)
+	return lintVisitor.Findings
+}
+
+// WriteLintText writes each finding on its own line, as
+// "file:line:column: message [rule]".
+func WriteLintText(w io.Writer, findings []Finding) error {
+	for _, finding := range findings {
+		if _, err := fmt.Fprintln(w, finding); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortFindings sorts findings in order of file, line and column.
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+}
+
+// The LintVisitor collects the problems in each document it visits.
+type LintVisitor struct {
+	Symbols         SymbolMap
+	SkipPrivateDefs bool
+	Findings        []Finding
+}
+
+func (v *LintVisitor) visit(doc *Document) error {
+	if v.SkipPrivateDefs {
+		if doc.Blocks[0].Attributes["visibility"] == "private" {
+			return nil
+		}
+		doc = doc.withoutPrivate()
+	}
+
+	for _, link := range links(doc) {
+		_, linkErr := v.Symbols.Resolve(doc, linkTarget(doc, link.Lexeme))
+		if linkErr == nil {
+			continue
+		}
+
+		v.report(doc, link.Range, linkErr.(*LinkError).Code(), linkErr.Error())
+	}
+
+	for i := range doc.Blocks {
+		block := &doc.Blocks[i]
+
+		for _, tag := range block.UnclosedTags {
+			v.report(doc, tag.Range, "unclosed-tag", "{"+tag.Lexeme+" is never closed")
+		}
+
+		v.checkHTML(doc, block.Text)
+		for _, key := range sortedKeys(block.Tags) {
+			v.checkHTML(doc, block.Tags[key])
+		}
+		for _, key := range sortedKeys(block.Params) {
+			v.checkHTML(doc, block.Params[key])
+		}
+
+		v.checkSee(doc, block)
+
+		if isUndocumented(block) {
+			if isPublicAPI(doc, block) {
+				v.report(doc, block.Declaration, "undocumented", fmt.Sprintf("%s %s %s is undocumented", block.Attributes["visibility"], block.Type, describe(block)))
+			}
+			continue
+		}
+
+		if block.Type == SYM_TYPE_METHOD {
+			v.checkParams(doc, block)
+			v.checkReturn(doc, block)
+		}
+	}
+
+	return nil
+}
+
+func (v *LintVisitor) report(doc *Document, at Range, rule string, message string) {
+	v.Findings = append(v.Findings, Finding{
+		File:      doc.Address,
+		Line:      at.Start.Line,
+		Column:    at.Start.Column,
+		EndLine:   at.End.Line,
+		EndColumn: at.End.Column,
+		Rule:      rule,
+		Message:   message,
+	})
+}
+
+// describe returns the name a block is reported by, i.e. "add(int,int)".
+func describe(block *Block) string {
+	if block.QualifiedName != "" {
+		return block.QualifiedName
+	}
+	return block.Name
+}
+
+// isUndocumented returns whether a block's comment is empty.
+func isUndocumented(block *Block) bool {
+	if len(block.Tags) > 0 || len(block.Params) > 0 {
+		return false
+	}
+
+	for _, token := range block.Text {
+		if token.Type != TOK_JDOC_NL && strings.TrimSpace(token.Lexeme) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkSee reports @see tags which refer to a symbol which can't be found.
+// Strings and HTML links aren't checked.
+func (v *LintVisitor) checkSee(doc *Document, block *Block) {
+	see := block.Tags["@see"]
+
+	for _, line := range see.Lines() {
+		if line[0].Type != TOK_JDOC_LINE {
+			continue
+		}
+
+		reference, _ := splitLink(line[0].Lexeme)
+		if reference == "" || strings.HasPrefix(reference, "\"") {
+			continue
+		}
+
+		if _, err := v.Symbols.Resolve(doc, linkTarget(doc, reference)); err != nil {
+			v.report(doc, line[0].Range, "unresolved-see", "@see refers to "+reference+", which can't be found")
+		}
+	}
+}
+
+// checkParams reports parameters without a @param tag, and @param tags which
+// don't name a parameter.
+func (v *LintVisitor) checkParams(doc *Document, block *Block) {
+	declared := map[string]bool{}
+
+	for _, arg := range block.Arguments {
+		declared[arg.Name] = true
+
+		if _, found := block.Params[arg.Name]; !found {
+			v.report(doc, block.Comment, "undocumented-param", fmt.Sprintf("parameter %s of %s is undocumented", arg.Name, describe(block)))
+		}
+	}
+
+	for _, name := range sortedKeys(block.Params) {
+		// Type parameters are documented as "@param <T>"
+		if declared[name] || strings.HasPrefix(name, "<") {
+			continue
+		}
+
+		at := block.Comment
+		if param := block.Params[name]; len(param) > 0 {
+			at = param[0].Range
+		}
+
+		v.report(doc, at, "unknown-param", fmt.Sprintf("@param %s doesn't match any parameter of %s", name, describe(block)))
+	}
+}
+
+// checkReturn reports methods which return something, but have no @return tag.
+func (v *LintVisitor) checkReturn(doc *Document, block *Block) {
+	if _, found := block.Tags["@return"]; found {
+		return
+	}
+
+	if t := returnType(block); t != "" && t != "void" {
+		v.report(doc, block.Comment, "missing-return", fmt.Sprintf("%s returns %s, but has no @return", describe(block), t))
+	}
+}
+
+var modifiers = map[string]bool{
+	"abstract": true, "default": true, "final": true, "native": true, "private": true,
+	"protected": true, "public": true, "static": true, "strictfp": true, "synchronized": true,
+}
+
+var spaceBeforeBracket = regexp.MustCompile(`\s+([>\[\],])`)
+var spaceAfterBracket = regexp.MustCompile(`([<\[])\s+`)
+
+// returnType returns the return type from a method's definition, or nothing
+// for constructors. Type arguments are kept whole, i.e. "Map<String, T>".
+func returnType(block *Block) string {
+	i := strings.Index(block.Definition, block.Name+"(")
+	if i == -1 {
+		return ""
+	}
+
+	// Definitions are written token by token, so type arguments may be
+	// spaced out, i.e. "Map<String, List<T >>"
+	before := strings.TrimRightFunc(block.Definition[:i], unicode.IsSpace)
+	before = spaceBeforeBracket.ReplaceAllString(before, "$1")
+	before = spaceAfterBracket.ReplaceAllString(before, "$1")
+
+	// Walk back over the type, balancing its type arguments and brackets
+	start, depth := len(before), 0
+	for start > 0 {
+		ch, width := utf8.DecodeLastRuneInString(before[:start])
+
+		switch {
+		case ch == '>' || ch == ']':
+			depth++
+		case ch == '<' || ch == '[':
+			depth--
+		case depth == 0 && !isIdentifierRune(ch) && ch != '.':
+			goto found
+		}
+
+		start -= width
+	}
+
+found:
+	t := before[start:]
+	if t == "" || strings.HasPrefix(t, "<") || modifiers[erasure(t)] || strings.HasSuffix(before[:start], "@") {
+		return ""
+	}
+
+	return t
+}
+
+// Elements whose end tags may be left out.
+var optionalEndTags = map[string]bool{
+	"dd": true, "dt": true, "li": true, "p": true, "tbody": true, "td": true,
+	"tfoot": true, "th": true, "thead": true, "tr": true,
+}
+
+// checkHTML reports HTML elements in text which are closed out of order, or
+// never closed at all. Anything which isn't an HTML element, such as the
+// <String> in List<String>, is skipped.
+func (v *LintVisitor) checkHTML(doc *Document, text Text) {
+	var open []Token
+
+	for _, token := range text {
+		if token.Type != TOK_JSX_O && token.Type != TOK_JSX_X {
+			continue
+		}
+
+		name := htmlElementName(token)
+		if !htmlElements[name] || voidElements[name] || strings.HasSuffix(token.Lexeme, "/>") {
+			continue
+		}
+
+		if token.Type == TOK_JSX_O {
+			open = append(open, token)
+			continue
+		}
+
+		// Close any elements which needn't be, until we find the match
+		i := len(open) - 1
+		for i >= 0 && htmlElementName(open[i]) != name && optionalEndTags[htmlElementName(open[i])] {
+			i--
+		}
+
+		if i < 0 || htmlElementName(open[i]) != name {
+			v.report(doc, token.Range, "malformed-html", "unexpected "+token.Lexeme)
+			continue
+		}
+
+		open = open[:i]
+	}
+
+	for _, token := range open {
+		if name := htmlElementName(token); !optionalEndTags[name] {
+			v.report(doc, token.Range, "malformed-html", "<"+name+"> is never closed")
+		}
+	}
+}
+
+func htmlElementName(token Token) string {
+	tag := XMLTag{Tag: token.Lexeme}
+	return strings.ToLower(tag.Type())
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"testing"
+)
+
+func lint(t *testing.T, input string) []Finding {
+	docs := make(chan *Document, 1)
+	docs <- ParseDocument(BeginScanningJavaCode("Test", input), "Test.java")
+	close(docs)
+
+	return LintDocuments(&VisitorConfigOptions{}, docs)
+}
+
+func expectFindings(t *testing.T, findings []Finding, expected []string) {
+	t.Helper()
+
+	if len(findings) != len(expected) {
+		t.Fatalf("got %d findings %v, wanted %d", len(findings), findings, len(expected))
+	}
+
+	for i, finding := range findings {
+		if finding.String() != expected[i] {
+			t.Errorf("got %q, wanted %q", finding.String(), expected[i])
+		}
+	}
+}
+
+func TestLintCleanDocument(t *testing.T) {
+	input := `
+/**
+ * A class, see {@link #add(int, int)}.
+ */
+public class Test {
+	/**
+	 * Adds <b>two</b> numbers together
+	 *
+	 * @param a the first number
+	 * @param b the second number
+	 * @return the sum
+	 */
+	public int add(int a, int b);
+
+	/**
+	 * Does nothing
+	 *
+	 * @see Test#add(int, int)
+	 */
+	public Test();
+}`
+
+	expectFindings(t, lint(t, input), nil)
+}
+
+func TestLintLinks(t *testing.T) {
+	input := `
+/**
+ * A class, see {@link #missing()}.
+ *
+ * @see Nowhere
+ * @see "a string"
+ * @see <a href="https://example.com">Example</a>
+ */
+public class Test {
+	/**
+	 * Overloaded, see {@link #add}.
+	 */
+	public void add();
+
+	/**
+	 * Overloaded
+	 */
+	public void add(Test other);
+}`
+
+	expectFindings(t, lint(t, input), []string{
+		"Test.java:3:24: unresolved link to Test#missing() [unresolved-link]",
+		"Test.java:5:8: @see refers to Nowhere, which can't be found [unresolved-see]",
+		"Test.java:11:28: ambiguous link to Test#add (matches Test#add(), Test#add(Test)) [ambiguous-link]",
+		"Test.java:15:2: parameter other of add(Test) is undocumented [undocumented-param]",
+	})
+}
+
+func TestLintDeclarations(t *testing.T) {
+	input := `
+/**
+ * A class
+ */
+public class Test {
+	/**
+	 * Adds two numbers together
+	 *
+	 * @param a the first number
+	 * @param c a number which doesn't exist
+	 * @param <T> a type parameter
+	 */
+	public <T> int add(int a, int b);
+
+	/**
+	 */
+	public void empty();
+
+	/**
+	 */
+	private void hidden();
+
+	/**
+	 * Groups the values
+	 */
+	public static <T> Map<String, List<T>> group(List<T> values);
+
+	/**
+	 * Creates a test
+	 */
+	public <T> Test(T value);
+
+	/**
+	 * Maps the values
+	 */
+	public Map<K, V> [] maps();
+}`
+
+	expectFindings(t, lint(t, input), []string{
+		"Test.java:6:2: parameter b of add(int,int) is undocumented [undocumented-param]",
+		"Test.java:6:2: add(int,int) returns int, but has no @return [missing-return]",
+		"Test.java:10:12: @param c doesn't match any parameter of add(int,int) [unknown-param]",
+		"Test.java:17:2: public method empty() is undocumented [undocumented]",
+		"Test.java:23:2: parameter values of group(List) is undocumented [undocumented-param]",
+		"Test.java:23:2: group(List) returns Map<String, List<T>>, but has no @return [missing-return]",
+		"Test.java:28:2: parameter value of Test(T) is undocumented [undocumented-param]",
+		"Test.java:33:2: maps() returns Map<K, V>[], but has no @return [missing-return]",
+	})
+}
+
+func TestLintUndocumentedVisibility(t *testing.T) {
+	input := `
+/**
+ * A listener
+ */
+public interface Test {
+	void first();
+
+	int second(String name);
+
+	/**
+	 * The kinds of event
+	 */
+	enum Kind {
+		OPENED,
+		/** Closed */
+		CLOSED;
+	}
+}
+
+/**
+ * Package-private, so its members aren't part of the API
+ */
+class Helper {
+	public void help();
+}`
+
+	expectFindings(t, lint(t, input), []string{
+		"Test.java:6:2: public method first() is undocumented [undocumented]",
+		"Test.java:8:2: public method second(String) is undocumented [undocumented]",
+		"Test.java:14:3: public field OPENED is undocumented [undocumented]",
+	})
+}
+
+func TestLintMarkup(t *testing.T) {
+	input := `
+/**
+ * A {@code List<String>} with <b>bold <i>text</b></i>.
+ *
+ * <p>Paragraphs <ul><li>needn't be closed</ul>
+ * <div>but this is never closed, and {@code neither
+ */
+public class Test {
+}`
+
+	expectFindings(t, lint(t, input), []string{
+		"Test.java:3:32: <b> is never closed [malformed-html]",
+		"Test.java:3:47: unexpected </b> [malformed-html]",
+		"Test.java:6:4: <div> is never closed [malformed-html]",
+		"Test.java:6:40: {@code is never closed [unclosed-tag]",
+	})
+}
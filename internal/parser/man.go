@@ -0,0 +1,210 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/dburkart/javadoc2md/internal/logger"
+)
+
+// ManPageData is handed to the "class" template of the man flavor. Every
+// field but PageData is already formatted as roff.
+type ManPageData struct {
+	PageData
+	Command     string
+	Section     string
+	Summary     string
+	Synopsis    string
+	Description string
+	Deprecated  string
+	SeeAlso     []string
+}
+
+// The ManVisitor is responsible for emitting a man(7) page for each selected
+// class, which is any class listed in Classes or annotated with Annotation.
+// This is intended for the entry points of command-line tools.
+type ManVisitor struct {
+	OutputDirectory string
+	Section         string
+	Classes         []string // Simple or qualified class names
+	Annotation      string   // Simple name of the annotation, i.e. "Command"
+	Symbols         SymbolMap
+	Templates       *template.Template
+	Flavor          *Flavor
+}
+
+// Since SEE ALSO needs to know about every selected class, pages are written
+// by finish rather than as each document is visited.
+func (m *ManVisitor) visit(doc *Document) error {
+	return nil
+}
+
+func (m *ManVisitor) finish(docs []*Document) error {
+	// Map every selected class to its command
+	commands := map[string]string{}
+	var selected []*Document
+	for _, doc := range docs {
+		if !doc.HasClass() || !m.selects(doc) {
+			continue
+		}
+
+		command := commandName(&doc.Blocks[0])
+		commands[doc.Blocks[0].Name] = command
+		commands[doc.Package+"."+doc.Blocks[0].Name] = command
+		selected = append(selected, doc)
+	}
+
+	if len(selected) == 0 {
+		logger.Report(logger.Diagnostic{
+			Level:   logger.LOG_LEVEL_WARN,
+			Code:    "no-man-pages",
+			Message: "no classes were selected for man pages; use -man-classes or -man-annotation",
+		})
+	}
+
+	for _, doc := range selected {
+		data := m.pageData(doc, commands)
+
+		var page bytes.Buffer
+		if err := m.Templates.ExecuteTemplate(&page, "class", data); err != nil {
+			return err
+		}
+
+		err := writeIfChanged(filepath.Join(m.OutputDirectory, data.Command+"."+m.Section), page.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *ManVisitor) selects(doc *Document) bool {
+	class := &doc.Blocks[0]
+
+	for _, name := range m.Classes {
+		if name == class.Name || name == doc.Package+"."+class.Name {
+			return true
+		}
+	}
+
+	if m.Annotation == "" {
+		return false
+	}
+
+	for _, annotation := range class.Annotations {
+		name, _, _ := strings.Cut(strings.TrimPrefix(annotation, "@"), "(")
+		if name == m.Annotation || strings.HasSuffix(name, "."+m.Annotation) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var annotationName = regexp.MustCompile(`\bname\s*=\s*"([^"]+)"`)
+
+// commandName returns the name of the command documented by a class, which
+// is taken from a name attribute on its annotations (as used by picocli), or
+// is otherwise the lowercased class name.
+func commandName(class *Block) string {
+	for _, annotation := range class.Annotations {
+		if m := annotationName.FindStringSubmatch(annotation); m != nil {
+			return m[1]
+		}
+	}
+
+	return strings.ToLower(class.Name)
+}
+
+func (m *ManVisitor) pageData(doc *Document, commands map[string]string) ManPageData {
+	class := &doc.Blocks[0]
+	syntax := m.Flavor.Syntax
+
+	data := ManPageData{
+		PageData:    makePageData(doc, m.Symbols, m.Flavor),
+		Command:     commandName(class),
+		Section:     m.Section,
+		Description: roffParagraphs(class.Text.Interpolate(doc, m.Symbols, m.Flavor, "")),
+	}
+
+	// The summary is the first sentence of the first paragraph
+	paragraph, _, _ := strings.Cut(data.Description, "\n.")
+	data.Summary = firstSentence(paragraph)
+
+	data.Synopsis = ".B " + syntax.Literal(data.Command)
+	for _, tag := range []string{"@synopsis", "@usage"} {
+		if synopsis, found := class.Tags[tag]; found {
+			data.Synopsis = roffParagraphs(synopsis.Interpolate(doc, m.Symbols, m.Flavor, ""))
+			break
+		}
+	}
+
+	if deprecated, found := class.Tags["@deprecated"]; found {
+		data.Deprecated = roffParagraphs(deprecated.Interpolate(doc, m.Symbols, m.Flavor, ""))
+	}
+
+	see := class.Tags["@see"]
+	for _, line := range see.Lines() {
+		target := strings.TrimSpace(line[0].Lexeme)
+
+		// References to other commands are written the way man pages expect
+		if command, found := commands[target]; found && len(line) == 1 {
+			data.SeeAlso = append(data.SeeAlso, "\\fB"+syntax.Literal(command)+"\\fR("+m.Section+")")
+			continue
+		}
+
+		// Anything else is either a string, a link, or a Java reference
+		if len(line) == 1 && !strings.HasPrefix(target, "\"") {
+			data.SeeAlso = append(data.SeeAlso, syntax.Emphasis(target))
+			continue
+		}
+
+		data.SeeAlso = append(data.SeeAlso, strings.Trim(line.Interpolate(doc, m.Symbols, m.Flavor, ""), "\""))
+	}
+
+	return data
+}
+
+// roffParagraphs tidies interpolated text into valid roff: blank lines
+// become paragraph breaks, and since leading whitespace forces a line break
+// in roff, it's removed outside of no-fill regions.
+func roffParagraphs(text string) string {
+	var lines []string
+	fill := true
+	paragraph := false
+
+	for _, line := range strings.Split(text, "\n") {
+		if fill {
+			line = strings.TrimSpace(line)
+		}
+
+		switch {
+		case line == ".nf":
+			fill = false
+		case line == ".fi":
+			fill = true
+		case line == "" && fill, line == ".PP":
+			paragraph = len(lines) > 0
+			continue
+		}
+
+		if paragraph {
+			lines = append(lines, ".PP")
+			paragraph = false
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
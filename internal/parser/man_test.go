@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManPage(t *testing.T) {
+	sources := map[string]string{
+		"Greet.java": `
+package com.example;
+
+/**
+ * Prints a greeting to standard output. Use {@code --name} to pick who is
+ * greeted.
+ *
+ * <p>Exits with status 0.
+ *
+ * @synopsis greet [--name NAME]
+ * @see Farewell
+ * @see "The greeting guide"
+ */
+@Command(name = "greet", mixinStandardHelpOptions = true)
+public class Greet {
+	public static void main(String[] args);
+}`,
+		"Farewell.java": `
+package com.example;
+
+/**
+ * Says goodbye.
+ */
+public class Farewell {
+}`,
+		"Helper.java": `
+package com.example;
+
+/**
+ * Not a command.
+ */
+public class Helper {
+}`,
+	}
+	directory := t.TempDir()
+
+	docs := make(chan *Document, len(sources))
+	for name, source := range sources {
+		docs <- ParseDocument(BeginScanningJavaCode(name, source), name)
+	}
+	close(docs)
+
+	options := VisitorConfigOptions{
+		OutputDirectory: directory,
+		Flavor:          Flavors["man"],
+		ManClasses:      []string{"com.example.Farewell"},
+		ManAnnotation:   "Command",
+	}
+	if err := VisitDocuments(&options, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(directory, "greet.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `.TH GREET 1
+.SH NAME
+greet \- Prints a greeting to standard output.
+.SH SYNOPSIS
+greet [\-\-name NAME]
+.SH DESCRIPTION
+Prints a greeting to standard output. Use \fB\-\-name\fR to pick who is
+greeted.
+.PP
+Exits with status 0.
+.SH SEE ALSO
+\fBfarewell\fR(1),
+The greeting guide
+`
+	if string(page) != expected {
+		t.Errorf("got:\n%s\nwanted:\n%s", page, expected)
+	}
+
+	if _, err := os.Stat(filepath.Join(directory, "farewell.1")); err != nil {
+		t.Errorf("expected farewell.1 to be written: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(directory, "helper.1")); err == nil {
+		t.Errorf("expected helper.1 not to be written")
+	}
+}
+
+func TestRoffParagraphsEscapeRequests(t *testing.T) {
+	input := `
+/**
+ * Reads the configuration from
+ * .foo in the current directory, or
+ * .PP if there isn't one, and
+ * 'quoted files. A &#92; is a backslash.
+ */
+public class Config {}`
+
+	d := ParseDocument(BeginScanningJavaCode("Test", input), "Config.java")
+
+	text := roffParagraphs(d.Blocks[0].Text.Interpolate(d, SymbolMap{}, Flavors["man"], ""))
+	expected := "Reads the configuration from\n" +
+		"\\&.foo in the current directory, or\n" +
+		"\\&.PP if there isn't one, and\n" +
+		"\\&'quoted files. A \\e is a backslash."
+	if text != expected {
+		t.Errorf("got %q, wanted %q", text, expected)
+	}
+}
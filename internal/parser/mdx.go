@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HTML elements which may be passed through to MDX as JSX. Anything else
+// which looks like a tag (i.e. the <T> in List<T>) is a generic type.
+var htmlElements = map[string]bool{
+	"a": true, "abbr": true, "b": true, "blockquote": true, "br": true,
+	"caption": true, "cite": true, "code": true, "dd": true, "del": true,
+	"dfn": true, "div": true, "dl": true, "dt": true, "em": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+	"i": true, "img": true, "ins": true, "kbd": true, "li": true, "ol": true,
+	"p": true, "pre": true, "q": true, "s": true, "samp": true, "small": true,
+	"span": true, "strong": true, "sub": true, "sup": true, "table": true,
+	"tbody": true, "td": true, "tfoot": true, "th": true, "thead": true,
+	"tr": true, "tt": true, "u": true, "ul": true, "var": true,
+}
+
+// Elements which never have content, and so must always be self-closed.
+var voidElements = map[string]bool{
+	"br": true, "hr": true, "img": true,
+}
+
+var mdxEscaper = strings.NewReplacer("{", "\\{", "}", "\\}", "<", "&lt;")
+
+// escapeMDX escapes the characters in prose which MDX would otherwise treat
+// as the start of an expression or JSX tag.
+func escapeMDX(s string) string {
+	return mdxEscaper.Replace(s)
+}
+
+var styleAttribute = regexp.MustCompile(`\s+style\s*=\s*("[^"]*"|'[^']*')`)
+var classAttribute = regexp.MustCompile(`(\s)class(\s*=)`)
+
+// jsxAttributes rewrites the attributes of an HTML tag into ones JSX will
+// accept. Inline styles are dropped, since JSX expects an object.
+func jsxAttributes(tag string) string {
+	tag = styleAttribute.ReplaceAllString(tag, "")
+	return classAttribute.ReplaceAllString(tag, "${1}className${2}")
+}
+
+// splitTrailingWord splits s before the identifier (or qualified name) it
+// ends with, if any.
+func splitTrailingWord(s string) (head string, word string) {
+	i := len(s)
+	for i > 0 {
+		c, width := utf8.DecodeLastRuneInString(s[:i])
+		if !isIdentifierRune(c) && c != '.' {
+			break
+		}
+		i -= width
+	}
+
+	return s[:i], s[i:]
+}
+
+// An MDXError describes a construct on a particular line of a page which
+// will fail to compile as MDX.
+type MDXError struct {
+	Line    int
+	Message string
+}
+
+func (e *MDXError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+var headingID = regexp.MustCompile(`^#+ .*(\{#[^}]*\})\s*$`)
+
+// ValidateMDX checks that a rendered page will compile as MDX, returning an
+// error for each problem found. Code blocks and code spans are skipped, since
+// their content is never interpreted.
+func ValidateMDX(page string) []error {
+	var errors []error
+	var open stack
+	inFence := false
+
+	for n, line := range strings.Split(page, "\n") {
+		lineNumber := n + 1
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+
+		if inFence {
+			continue
+		}
+
+		// Docusaurus strips heading IDs before the page reaches MDX
+		if m := headingID.FindStringSubmatchIndex(line); m != nil {
+			line = line[:m[2]]
+		}
+
+		for i := 0; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '`':
+				run := 1
+				for i+run < len(line) && line[i+run] == '`' {
+					run++
+				}
+
+				closing := strings.Index(line[i+run:], strings.Repeat("`", run))
+				if closing == -1 {
+					i += run - 1
+				} else {
+					i += run + closing + run - 1
+				}
+			case '{', '}':
+				errors = append(errors, &MDXError{lineNumber, fmt.Sprintf("unescaped %q", line[i])})
+			case '<':
+				end := strings.IndexByte(line[i:], '>')
+				if end == -1 || i+1 == len(line) || !(unicode.IsLetter(rune(line[i+1])) || line[i+1] == '/') {
+					errors = append(errors, &MDXError{lineNumber, "unescaped '<'"})
+					continue
+				}
+
+				tag := XMLTag{Index: lineNumber, Tag: line[i : i+end+1]}
+				i += end
+
+				if strings.Contains(strings.Fields(tag.Tag)[0], ":") {
+					errors = append(errors, &MDXError{lineNumber, "autolinks are not supported: " + tag.Tag})
+					continue
+				}
+
+				if strings.HasSuffix(tag.Tag, "/>") {
+					continue
+				}
+
+				if !strings.HasPrefix(tag.Tag, "</") {
+					open.Push(tag)
+					continue
+				}
+
+				top, empty := open.Pop()
+				if empty {
+					errors = append(errors, &MDXError{lineNumber, "unexpected closing tag " + tag.Tag})
+				} else if top.Type() != tag.Type() {
+					errors = append(errors, &MDXError{lineNumber, fmt.Sprintf("expected closing tag for <%s> (line %d), found %s", top.Type(), top.Index, tag.Tag)})
+				}
+			}
+		}
+	}
+
+	for _, tag := range open {
+		errors = append(errors, &MDXError{tag.Index, fmt.Sprintf("<%s> is never closed", tag.Type())})
+	}
+
+	return errors
+}
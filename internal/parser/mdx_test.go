@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMDXInterpolation(t *testing.T) {
+	input := `
+/**
+ * Returns a List<String> or a Map<String, List<T>> of {values}.
+ * <p>
+ * Stray tags</b> are <BR> dropped <div class="x" style="color: red">here</div>.
+ * <!-- Nobody will see this -->
+ * <pre>
+ * Map<String, T> m = new HashMap<>() { };
+ * </pre>
+ */
+public class Generics {}`
+
+	s := BeginScanningJavaCode("Test", input)
+	d := ParseDocument(s, "Generics.java")
+
+	text := d.Blocks[0].Text.Interpolate(d, SymbolMap{}, Flavors["mdx"], "")
+	expected := "Returns a `List<String>` or a `Map<String, List<T>>` of \\{values\\}.\n" +
+		"<p/>\n" +
+		"Stray tags are <br/> dropped <div className=\"x\">here</div>.\n" +
+		"\n" +
+		"```java\n" +
+		"Map<String, T> m = new HashMap<>() { };\n" +
+		"```"
+
+	if text != expected {
+		t.Errorf("got %q, wanted %q", text, expected)
+	}
+
+	if errors := ValidateMDX(text); len(errors) != 0 {
+		t.Errorf("expected valid MDX, got %v", errors)
+	}
+}
+
+func TestMDXInterpolationNonASCII(t *testing.T) {
+	input := `
+/**
+ * Returns the Größe<T> of a Maß.
+ */
+public class Sizes {}`
+
+	d := ParseDocument(BeginScanningJavaCode("Test", input), "Sizes.java")
+
+	text := d.Blocks[0].Text.Interpolate(d, SymbolMap{}, Flavors["mdx"], "")
+	expected := "Returns the `Größe<T>` of a Maß."
+	if text != expected {
+		t.Errorf("got %q, wanted %q", text, expected)
+	}
+
+	if !utf8.ValidString(text) {
+		t.Errorf("got invalid UTF-8 in %q", text)
+	}
+}
+
+func TestValidateMDX(t *testing.T) {
+	page := "### `foo()` {#foo()}\n\n" +
+		"Some `{code}` and\n" +
+		"```java\nif (x < y) { }\n```\n" +
+		"a {brace} and <b>bold</i> and <https://example.com>\n" +
+		"<p>\n"
+
+	var messages []string
+	for _, err := range ValidateMDX(page) {
+		messages = append(messages, err.Error())
+	}
+
+	expected := []string{
+		"line 7: unescaped '{'",
+		"line 7: unescaped '}'",
+		"line 7: expected closing tag for <b> (line 7), found </i>",
+		"line 7: autolinks are not supported: <https://example.com>",
+		"line 8: <p> is never closed",
+	}
+
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got %q, wanted %q", messages, expected)
+	}
+}
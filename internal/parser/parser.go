@@ -7,10 +7,10 @@
 package parser
 
 import (
-	"fmt"
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/dburkart/javadoc2md/internal/logger"
 )
@@ -61,90 +61,79 @@ func splitKey(line string) (head string, remainder string) {
 }
 
 func ParseDocument(scanner *Scanner, path string) *Document {
-	// First, set up our scan loop
-	go func() {
-		for {
-			scanner.State = scanner.State(scanner)
-			if scanner.State == nil {
-				break
-			}
-		}
-	}()
-
 	doc := MakeDocument(path)
 
-	t := <-scanner.Tokens
-
-	// If we see a package name, save that aside before processing any Javadocs
-	if t.Type == TOK_JAVA_KEYWORD && t.Lexeme == "package" {
-		t = <-scanner.Tokens
-		// TODO: What if it's not an identifier?
-		doc.Package = t.Lexeme
-		t = <-scanner.Tokens
-	}
+	t := scanner.NextToken()
 
 	for {
 		if t.Type == TOK_EOF {
 			break
 		}
 
-		// Skip anything which isn't the start of javadoc comment
-		if t.Type != TOK_JDOC_START {
-			t = <-scanner.Tokens
-			continue
+		switch {
+		case t.Type == TOK_JAVA_KEYWORD && t.Lexeme == "package":
+			t = scanner.NextToken()
+			// TODO: What if it's not an identifier?
+			doc.Package = t.Lexeme
+			t = scanner.NextToken()
+		case t.Type == TOK_JAVA_KEYWORD && t.Lexeme == "import":
+			t = parseImport(scanner, doc)
+		case t.Type == TOK_JDOC_START:
+			t = ParseJavadoc(scanner, doc, t)
+		case t.Type >= TOK_JAVA_KEYWORD:
+			t = parseUndocumented(scanner, doc, t)
+		default:
+			t = scanner.NextToken()
 		}
-
-		t = ParseJavadoc(scanner, doc, t)
 	}
 
 	return doc
 }
 
+// parseImport records the import statement following an "import" keyword,
+// and returns the token after it.
+func parseImport(scanner *Scanner, document *Document) Token {
+	imp := Import{}
+
+	t := scanner.NextToken()
+	if t.Type == TOK_JAVA_KEYWORD && t.Lexeme == "static" {
+		imp.Static = true
+		t = scanner.NextToken()
+	}
+
+	if t.Type != TOK_JAVA_IDENTIFIER {
+		return t
+	}
+
+	imp.Name = t.Lexeme
+	document.Imports = append(document.Imports, imp)
+
+	return scanner.NextToken()
+}
+
 func ParseJavadoc(scanner *Scanner, document *Document, t Token) Token {
 	if t.Type != TOK_JDOC_START {
 		return t
 	}
 
-	inParam := false
-	var paramContents *Token
-
 	// Make our Javadoc block
 	block := MakeBlock()
 	block.Doc = document
+	block.Comment = t.Range
 
 	// Pull off lines until we hit the first Tag
 	for {
-		t = <-scanner.Tokens
-
-		if t.Type == TOK_JDOC_PARAM {
-			inParam = true
-			block.Text = append(block.Text, t)
-			continue
-		}
+		t = scanner.NextToken()
 
-		if t.Type == TOK_JDOC_PARAM_END {
-			inParam = false
-			if paramContents != nil {
-				block.Text = append(block.Text, *paramContents)
-			}
-			paramContents = nil
-			continue
-		}
-
-		if inParam && (t.Type == TOK_JDOC_LINE || t.Type == TOK_JSX_O || t.Type == TOK_JSX_X) {
-			if paramContents == nil {
-				paramContents = &Token{}
-				paramContents.Type = t.Type
-				paramContents.Lexeme = t.Lexeme
-			} else {
-				paramContents.Lexeme = paramContents.Lexeme + t.Lexeme
-			}
-		} else if t.Type == TOK_JDOC_LINE || t.Type == TOK_JDOC_NL || t.Type == TOK_JSX_O || t.Type == TOK_JSX_X {
-			block.Text = append(block.Text, t)
-		} else {
+		if t.Type != TOK_JDOC_LINE && t.Type != TOK_JDOC_PARAM && t.Type != TOK_JDOC_PARAM_END &&
+			t.Type != TOK_JDOC_NL && t.Type != TOK_JSX_O && t.Type != TOK_JSX_X {
 			break
 		}
+
+		block.Text = append(block.Text, t)
 	}
+	block.UnclosedTags = unclosedTags(block.Text)
+	block.Text = mergeParamContents(block.Text)
 
 	// Add tags to the Tag map for the block, until we hit a non-Tag
 	for {
@@ -152,7 +141,7 @@ func ParseJavadoc(scanner *Scanner, document *Document, t Token) Token {
 			break
 		}
 
-		val := <-scanner.Tokens
+		val := scanner.NextToken()
 		tagKey := t.Lexeme
 
 		if t.Lexeme == "@param" {
@@ -168,17 +157,12 @@ func ParseJavadoc(scanner *Scanner, document *Document, t Token) Token {
 				break
 			}
 
-			if val.Type == TOK_JDOC_PARAM_END {
-				val = <-scanner.Tokens
-				continue
-			}
-
 			if t.Lexeme == "@param" {
 				block.Params[tagKey] = append(block.Params[tagKey], val)
 			} else {
 				block.Tags[tagKey] = append(block.Tags[tagKey], val)
 			}
-			val = <-scanner.Tokens
+			val = scanner.NextToken()
 		}
 
 		if t.Type != TOK_JDOC_TAG {
@@ -186,8 +170,25 @@ func ParseJavadoc(scanner *Scanner, document *Document, t Token) Token {
 		}
 	}
 
+	for k, v := range block.Tags {
+		block.UnclosedTags = append(block.UnclosedTags, unclosedTags(v)...)
+		block.Tags[k] = mergeParamContents(v)
+	}
+
+	for k, v := range block.Params {
+		block.UnclosedTags = append(block.UnclosedTags, unclosedTags(v)...)
+		block.Params[k] = mergeParamContents(v)
+	}
+
 	if t.Type == TOK_JDOC_END {
-		t = <-scanner.Tokens
+		block.Comment.End = t.End
+		t = scanner.NextToken()
+	}
+
+	// A comment heading the file, before its package or imports, doesn't
+	// document anything
+	if t.Type == TOK_JAVA_KEYWORD && (t.Lexeme == "package" || t.Lexeme == "import") {
+		return t
 	}
 
 	t = ParseJavaContext(scanner, block, t)
@@ -196,31 +197,153 @@ func ParseJavadoc(scanner *Scanner, document *Document, t Token) Token {
 	document.Blocks = append(document.Blocks, *block)
 
 	if block.Name == "" {
-		logger.Debug("Could not introspect name from block " + fmt.Sprint(len(document.Blocks)) + " in document " + document.Address)
+		logger.Report(logger.Diagnostic{
+			Level:   logger.LOG_LEVEL_WARN,
+			Code:    "unnamed-block",
+			File:    document.Address,
+			Line:    block.Comment.Start.Line,
+			Column:  block.Comment.Start.Column,
+			Message: "could not introspect name from block",
+		})
 	}
 
 	return t
 }
 
-func ParseJavaContext(scanner *Scanner, block *Block, head Token) Token {
-	var lastToken Token
+// parseUndocumented parses a declaration which has no Javadoc comment into
+// a block with no text, and returns the token following it. Initializers,
+// which have no name, aren't declarations, so are dropped.
+func parseUndocumented(scanner *Scanner, document *Document, t Token) Token {
+	block := MakeBlock()
+	block.Doc = document
+
+	t = ParseJavaContext(scanner, block, t)
+	if block.Name == "" {
+		return t
+	}
 
+	block.Definition = FormatDefinition(block.Definition)
+	document.Blocks = append(document.Blocks, *block)
+
+	return t
+}
+
+// unclosedTags returns each inline tag in text which is never closed.
+func unclosedTags(text Text) []Token {
+	var result []Token
+	var open *Token
+
+	for i, t := range text {
+		if t.Type == TOK_JDOC_PARAM {
+			open = &text[i]
+		} else if t.Type == TOK_JDOC_PARAM_END {
+			if open != nil && t.Lexeme == "" {
+				result = append(result, *open)
+			}
+			open = nil
+		}
+	}
+
+	return result
+}
+
+// mergeParamContents joins the content of each inline tag, which may span
+// several lines, into the single token following the tag, which is empty if
+// the tag is. The lines of a link are joined with spaces, and any others
+// (i.e. code) as they are.
+func mergeParamContents(text Text) Text {
+	var result Text
+	var contents *Token
+	inParam, separator := false, ""
+
+	flush := func() {
+		if contents != nil {
+			result = append(result, *contents)
+		}
+		contents = nil
+	}
+
+	for _, t := range text {
+		switch {
+		case t.Type == TOK_JDOC_PARAM:
+			flush()
+			result = append(result, t)
+			inParam = true
+
+			separator = ""
+			if isLink(t.Lexeme) {
+				separator = " "
+			}
+		case t.Type == TOK_JDOC_PARAM_END:
+			// An empty tag (i.e. "{@code}") still has contents, so that the
+			// text following it isn't taken for them
+			if inParam && contents == nil {
+				contents = &Token{Type: TOK_JDOC_LINE, Range: Range{t.Start, t.Start}}
+			}
+			flush()
+			inParam = false
+		case inParam && (t.Type == TOK_JDOC_LINE || t.Type == TOK_JSX_O || t.Type == TOK_JSX_X):
+			if contents == nil {
+				contents = &Token{Type: t.Type, Lexeme: t.Lexeme, Range: t.Range}
+			} else {
+				contents.Lexeme += separator + t.Lexeme
+				contents.End = t.End
+			}
+		default:
+			result = append(result, t)
+		}
+	}
+	flush()
+
+	return result
+}
+
+func ParseJavaContext(scanner *Scanner, block *Block, head Token) Token {
 	t := head
-	lastID, lastLastID := "", ""
+	lastID := ""
 	inArgumentList := false
+
+	// Type keywords in initializers and class literals, i.e. "String.class",
+	// don't declare anything
+	initializer, previous := false, ""
+
+	// The tokens of the argument being read, and how deeply nested in type
+	// arguments we are (since they may contain commas), in the argument and
+	// in the declaration
+	var argument []Token
+	depth, typeDepth := 0, 0
+	if t.Type >= TOK_JAVA_KEYWORD {
+		block.Declaration = t.Range
+	}
+
+	context := scanner.Context()
+	block.Depth = context.Depth
+
 	for {
 		if t.Type < TOK_JAVA_KEYWORD {
 			if block.Name == "" {
 				block.Name = lastID
 			}
 
+			// Anything else named, such as a field without an initializer or
+			// an enum constant, is a field
+			if block.Type == SYM_TYPE_INVALID && block.Name != "" {
+				block.Type = SYM_TYPE_FIELD
+			}
+
+			setImplicitVisibility(block, context)
 			return t
 		}
 
 		block.Definition += " " + t.Lexeme
+		block.Declaration.End = t.End
+
+		if t.Type == TOK_JAVA_ANNOTATION && !inArgumentList {
+			block.Annotations = append(block.Annotations, t.Lexeme)
+		}
 
-		if t.Type == TOK_JAVA_KEYWORD || t.Type == TOK_JAVA_ANNOTATION {
-			if t.Lexeme == "public" || t.Lexeme == "private" {
+		if (t.Type == TOK_JAVA_KEYWORD || t.Type == TOK_JAVA_ANNOTATION) && !initializer && previous != "." {
+			if t.Lexeme == "public" || t.Lexeme == "protected" || t.Lexeme == "private" {
 				block.Attributes["visibility"] = t.Lexeme
 				goto next
 			}
@@ -237,25 +360,21 @@ func ParseJavaContext(scanner *Scanner, block *Block, head Token) Token {
 			if t.Lexeme == "class" || t.Lexeme == "interface" ||
 				t.Lexeme == "@class" || t.Lexeme == "@interface" ||
 				t.Lexeme == "enum" {
-				t = <-scanner.Tokens
+				t = scanner.NextToken()
 
 				block.Definition += " " + t.Lexeme
+				block.Declaration.End = t.End
 
 				block.Name = t.Lexeme
 				goto next
 			}
 		}
 
-		if t.Type == TOK_JAVA_IDENTIFIER {
-			lastLastID = lastID
-			lastID = t.Lexeme
-			goto next
-		}
-
 		if t.Type == TOK_JAVA_PAREN_O && block.Name == "" {
 			block.Name = lastID
 			inArgumentList = true
 			block.Type = SYM_TYPE_METHOD
+			goto next
 		}
 
 		if t.Type == TOK_JAVA_EQUAL && block.Name == "" {
@@ -263,26 +382,89 @@ func ParseJavaContext(scanner *Scanner, block *Block, head Token) Token {
 			block.Type = SYM_TYPE_FIELD
 		}
 
+		// The first of several fields declared together, i.e. "int a, b;"
+		if t.Type == TOK_JAVA_COMMA && block.Name == "" && !initializer && typeDepth == 0 {
+			block.Name = lastID
+			block.Type = SYM_TYPE_FIELD
+		}
+
+		if t.Type == TOK_JAVA_EQUAL || t.Lexeme == "default" {
+			initializer = true
+		}
+
+		if !inArgumentList && !initializer {
+			typeDepth += strings.Count(t.Lexeme, "<") - strings.Count(t.Lexeme, ">")
+		}
+
 		// Record our arguments
 		if inArgumentList {
-			pair := ArgPair{Type: lastLastID, Name: lastID}
-
-			if t.Type == TOK_JAVA_PAREN_X {
-				inArgumentList = false
-				// There were no arguments in this case
-				if lastToken.Type == TOK_JAVA_PAREN_O {
-					goto next
+			if (t.Type == TOK_JAVA_COMMA || t.Type == TOK_JAVA_PAREN_X) && depth == 0 {
+				if len(argument) > 0 {
+					block.Arguments = append(block.Arguments, makeArgPair(argument))
 				}
-				block.Arguments = append(block.Arguments, pair)
+				argument = nil
+				inArgumentList = t.Type != TOK_JAVA_PAREN_X
+				goto next
 			}
 
-			if t.Type == TOK_JAVA_COMMA {
-				block.Arguments = append(block.Arguments, pair)
+			depth += strings.Count(t.Lexeme, "<") - strings.Count(t.Lexeme, ">")
+
+			// Annotations and modifiers aren't part of the type
+			if t.Type != TOK_JAVA_ANNOTATION && !(t.Type == TOK_JAVA_IDENTIFIER && t.Lexeme == "final") {
+				argument = append(argument, t)
 			}
 		}
 
+		if t.Type == TOK_JAVA_IDENTIFIER {
+			lastID = t.Lexeme
+		}
+
 	next:
-		lastToken = t
-		t = <-scanner.Tokens
+		previous = t.Lexeme
+		t = scanner.NextToken()
+	}
+}
+
+// setImplicitVisibility records the visibility of declarations which are
+// public without saying so: enum constants, and the members of interfaces
+// (and annotations) which aren't private.
+func setImplicitVisibility(block *Block, context TokenContext) {
+	if _, found := block.Attributes["visibility"]; found || block.Doc == nil {
+		return
+	}
+
+	enclosing := enclosingType(block.Doc.Blocks, block.Depth)
+	if context.EnumConstant || (enclosing != nil && enclosing.Type == SYM_TYPE_INTERFACE) {
+		block.Attributes["visibility"] = "public"
+	}
+}
+
+// makeArgPair splits the tokens of an argument into its type and name.
+func makeArgPair(tokens []Token) ArgPair {
+	last := len(tokens) - 1
+	if tokens[last].Type != TOK_JAVA_IDENTIFIER || last == 0 {
+		return ArgPair{Type: joinType(tokens)}
 	}
+
+	return ArgPair{Type: joinType(tokens[:last]), Name: tokens[last].Lexeme}
+}
+
+// joinType joins the tokens of a type, i.e. "List<? extends Number>", with
+// spaces only between words.
+func joinType(tokens []Token) string {
+	var result strings.Builder
+
+	for i, t := range tokens {
+		if i > 0 && isWord(tokens[i-1].Lexeme) && isWord(t.Lexeme) {
+			result.WriteString(" ")
+		}
+		result.WriteString(t.Lexeme)
+	}
+
+	return result.String()
+}
+
+func isWord(lexeme string) bool {
+	r, _ := utf8.DecodeRuneInString(lexeme)
+	return isIdentifierRune(r) || r == '?'
 }
@@ -7,7 +7,15 @@
 package parser
 
 import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/dburkart/javadoc2md/internal/logger"
 )
 
 func TestSimpleClass(t *testing.T) {
@@ -37,3 +45,314 @@ public class SimpleClass {
 		t.Errorf("got %d arguments, wanted 2", len(d.Blocks[1].Arguments))
 	}
 }
+
+func TestSourcePositions(t *testing.T) {
+	input := `package foo;
+
+/**
+ * A ünïcode class, see {@link Bar}
+ */
+public class Simple {
+}`
+	s := BeginScanningJavaCode("Test Source Positions", input)
+	d := ParseDocument(s, "foo/Simple.java")
+
+	block := d.Blocks[0]
+	comment := Range{Position{14, 3, 1}, Position{59, 5, 4}}
+	if block.Comment != comment {
+		t.Errorf("got comment range %v, wanted %v", block.Comment, comment)
+	}
+
+	declaration := Range{Position{60, 6, 1}, Position{79, 6, 20}}
+	if block.Declaration != declaration {
+		t.Errorf("got declaration range %v, wanted %v", block.Declaration, declaration)
+	}
+
+	// Columns count characters, not bytes
+	link := links(d)[0]
+	if link.Start.Line != 4 || link.Start.Column != 32 {
+		t.Errorf("got link at %d:%d, wanted 4:32", link.Start.Line, link.Start.Column)
+	}
+}
+
+func TestUndocumentedDeclarations(t *testing.T) {
+	input := `/*
+ * Copyright header
+ */
+/** Not a class comment, since it's before the package */
+package foo;
+
+import java.util.List;
+
+public class Widget {
+	static {
+		register(String.class);
+	}
+
+	public static final String BRACES = "{ class Fake; }";
+
+	private Class<?> type = String.class;
+
+	/**
+	 * Makes a widget
+	 */
+	public Widget() {
+		char c = '}';
+		if (c == '{') {
+			int local = 0;
+		}
+	}
+
+	public void draw(int x, int y) {
+		// public void notAMember() {
+	}
+
+	public enum Color {
+		RED("red") {
+			public String toString() { return "Red"; }
+		},
+		GREEN("green");
+
+		Color(String name) {}
+	}
+
+	interface Listener {
+		void changed();
+	}
+}`
+	s := BeginScanningJavaCode("Test Undocumented Declarations", input)
+	d := ParseDocument(s, "foo/Widget.java")
+
+	if d.Package != "foo" {
+		t.Errorf("got package %q, wanted foo", d.Package)
+	}
+
+	expected := []struct {
+		name    string
+		comment bool
+	}{
+		{"Widget", false},
+		{"BRACES", false},
+		{"type", false},
+		{"Widget", true},
+		{"draw", false},
+		{"Color", false},
+		{"RED", false},
+		{"GREEN", false},
+		{"Color", false},
+		{"Listener", false},
+		{"changed", false},
+	}
+
+	if len(d.Blocks) != len(expected) {
+		for _, block := range d.Blocks {
+			t.Logf("%s %s", block.Type, block.Name)
+		}
+		t.Fatalf("got %d blocks, wanted %d", len(d.Blocks), len(expected))
+	}
+
+	for i, e := range expected {
+		block := d.Blocks[i]
+		if block.Name != e.name || block.HasComment() != e.comment {
+			t.Errorf("block %d: got %s (comment: %t), wanted %s (comment: %t)", i, block.Name, block.HasComment(), e.name, e.comment)
+		}
+	}
+
+	types := map[int]SymbolType{
+		0: SYM_TYPE_CLASS, 1: SYM_TYPE_FIELD, 2: SYM_TYPE_FIELD, 4: SYM_TYPE_METHOD, 5: SYM_TYPE_ENUM,
+		6: SYM_TYPE_FIELD, 7: SYM_TYPE_FIELD, 9: SYM_TYPE_INTERFACE,
+	}
+	for i, expectedType := range types {
+		if d.Blocks[i].Type != expectedType {
+			t.Errorf("got %s for %s, wanted %s", d.Blocks[i].Type, d.Blocks[i].Name, expectedType)
+		}
+	}
+
+	if len(d.Blocks[4].Arguments) != 2 {
+		t.Errorf("got %d arguments for draw, wanted 2", len(d.Blocks[4].Arguments))
+	}
+}
+
+func TestFieldDeclarations(t *testing.T) {
+	input := `
+/**
+ * Counts things, see {@link #count} and {@link Color#RED}
+ */
+public class Counter {
+	/** The count */
+	public int count;
+
+	/** Bounds */
+	private Map<String, Integer> lower, upper;
+
+	/** Colors */
+	public enum Color {
+		/** Red */
+		RED,
+		/** Green */
+		GREEN("green");
+	}
+}`
+	d := ParseDocument(BeginScanningJavaCode("Test Field Declarations", input), "Counter.java")
+
+	expected := []struct {
+		name       string
+		symbolType SymbolType
+	}{
+		{"Counter", SYM_TYPE_CLASS},
+		{"count", SYM_TYPE_FIELD},
+		{"lower", SYM_TYPE_FIELD},
+		{"Color", SYM_TYPE_ENUM},
+		{"RED", SYM_TYPE_FIELD},
+		{"GREEN", SYM_TYPE_FIELD},
+	}
+
+	if len(d.Blocks) != len(expected) {
+		t.Fatalf("got %d blocks, wanted %d", len(d.Blocks), len(expected))
+	}
+
+	for i, e := range expected {
+		if d.Blocks[i].Name != e.name || d.Blocks[i].Type != e.symbolType {
+			t.Errorf("block %d: got %s %s, wanted %s %s", i, d.Blocks[i].Type, d.Blocks[i].Name, e.symbolType, e.name)
+		}
+	}
+
+	// Links to fields resolve like links to anything else
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(d)
+
+	text := d.Blocks[0].Text.Interpolate(d, symbolVisitor.Symbols, Flavors["docusaurus"], "")
+	expectedText := "Counts things, see [`count`](Counter#count) and [`Color.RED`](Counter#RED)"
+	if strings.TrimSpace(text) != expectedText {
+		t.Errorf("got %q, wanted %q", text, expectedText)
+	}
+}
+
+func TestNonASCIIInput(t *testing.T) {
+	input := `/**
+ * Crème brûlée, 日本語 and 🎉
+ */
+public class Café {
+	/** Der Größte */
+	public int größe = a ? b : c % d;
+
+	/** Last */
+	public int y;
+}`
+	s := BeginScanningJavaCode("Test Non-ASCII Input", input)
+	d := ParseDocument(s, "Café.java")
+
+	names := []string{"Café", "größe", "y"}
+	if len(d.Blocks) != len(names) {
+		t.Fatalf("got %d blocks, wanted %d", len(d.Blocks), len(names))
+	}
+
+	for i, name := range names {
+		if d.Blocks[i].Name != name {
+			t.Errorf("got block named %q, wanted %q", d.Blocks[i].Name, name)
+		}
+	}
+
+	if text := d.Blocks[0].Text[1].Lexeme; text != "Crème brûlée, 日本語 and 🎉" {
+		t.Errorf("got text %q, wanted \"Crème brûlée, 日本語 and 🎉\"", text)
+	}
+
+	if text := d.Blocks[1].Text[0].Lexeme; text != "Der Größte " {
+		t.Errorf("got text %q, wanted \"Der Größte \"", text)
+	}
+}
+
+func TestUnnamedBlockWarning(t *testing.T) {
+	input := `/** Example */
+public class Example {
+	public int x;
+
+	/** Trailing */
+}`
+
+	var output bytes.Buffer
+	logger.SetOutput(&output)
+	defer logger.SetOutput(os.Stderr)
+
+	ParseDocument(BeginScanningJavaCode("Test Unnamed Block", input), "Example.java")
+
+	expected := "Example.java:5:2: warning: could not introspect name from block [unnamed-block]\n"
+	if output.String() != expected {
+		t.Errorf("got %q, wanted %q", output.String(), expected)
+	}
+}
+
+// generateClass returns the source of a class with the given number of
+// documented methods, each with a little of everything the scanner handles.
+func generateClass(methods int) string {
+	var source strings.Builder
+
+	source.WriteString("package com.example;\n\nimport java.util.List;\n\n")
+	source.WriteString("/**\n * A generated class, for benchmarking.\n */\npublic class Generated {\n")
+
+	for i := 0; i < methods; i++ {
+		fmt.Fprintf(&source, `
+	/**
+	 * Adds <b>%d</b> to each of the {@code values}, see {@link #method%d(List, int)}.
+	 *
+	 * @param values the values to add to
+	 * @param scale how much to scale the result by
+	 * @return the total
+	 */
+	public int method%d(List<Integer> values, int scale) {
+		int total = 0;
+		for (int value : values) {
+			total += value + %d; // Not a declaration
+		}
+		return total * scale;
+	}
+`, i, i, i, i)
+	}
+
+	source.WriteString("}\n")
+	return source.String()
+}
+
+func benchmarkParse(b *testing.B, sources []string) {
+	size := 0
+	for _, source := range sources {
+		size += len(source)
+	}
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for _, source := range sources {
+			ParseDocument(BeginScanningJavaCode("Benchmark", source), "Generated.java")
+		}
+	}
+}
+
+func BenchmarkParseDocument(b *testing.B) {
+	benchmarkParse(b, []string{generateClass(1000)})
+}
+
+// BenchmarkParseCorpus parses every Java file under the directory named by
+// JAVADOC2MD_CORPUS, i.e. an unpacked JDK src.zip.
+func BenchmarkParseCorpus(b *testing.B) {
+	corpus := os.Getenv("JAVADOC2MD_CORPUS")
+	if corpus == "" {
+		b.Skip("JAVADOC2MD_CORPUS isn't set")
+	}
+
+	var sources []string
+	err := filepath.WalkDir(corpus, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".java" {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		sources = append(sources, string(content))
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkParse(b, sources)
+}
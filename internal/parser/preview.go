@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+// RenderSite renders the HTML site for every document in memory, rather
+// than writing it, for previewing. The problems lint finds in each document
+// are shown on its page. Errors with single documents are returned as
+// FileErrors, in an ErrorList unless options.FailFast is set, along with the
+// rest of the site.
+func RenderSite(options *VisitorConfigOptions, docs chan *Document) (map[string][]byte, error) {
+	documents, symbols := collectSymbols(options, docs)
+
+	if options.SourceLinker != nil {
+		sourceVisitor := SourceVisitor{Linker: options.SourceLinker}
+		for _, doc := range documents {
+			sourceVisitor.visit(doc)
+		}
+	}
+
+	findings := make(map[string][]Finding)
+	for _, finding := range lintDocuments(options, documents, symbols) {
+		findings[finding.File] = append(findings[finding.File], finding)
+	}
+
+	flavor := Flavors["html"]
+	templates, err := LoadHTMLTemplates(flavor, options.TemplateDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlVisitor := HTMLVisitor{
+		SkipPrivateDefs:  options.SkipPrivateDefs,
+		FlagUndocumented: options.FlagUndocumented,
+		Symbols:          symbols,
+		Templates:        templates,
+		Flavor:           flavor,
+		Files:            make(map[string][]byte),
+		Findings:         findings,
+	}
+
+	var errs ErrorList
+	for _, doc := range documents {
+		if !doc.HasClass() {
+			continue
+		}
+
+		if err = htmlVisitor.visit(doc); err != nil {
+			docErr := &FileError{Op: "document", Path: doc.Address, Err: err}
+			if options.FailFast {
+				return nil, docErr
+			}
+			errs = append(errs, docErr)
+		}
+	}
+
+	if err = htmlVisitor.finish(documents); err != nil {
+		if options.FailFast {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+
+	return htmlVisitor.Files, errs.Err()
+}
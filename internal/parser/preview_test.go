@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderSite(t *testing.T) {
+	input := `package com.example;
+
+/**
+ * A class linking to {@link Missing}
+ */
+public class Simple {
+	/**
+	 * Adds two numbers together
+	 */
+	public int add(int a, int b);
+}`
+	docs := make(chan *Document, 1)
+	docs <- ParseDocument(BeginScanningJavaCode("Simple.java", input), "Simple.java")
+	close(docs)
+
+	files, err := RenderSite(&VisitorConfigOptions{OutputDirectory: t.TempDir()}, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"index.html", "package-com.example.html", "Simple.html", "style.css", "search.js", "search-index.js"} {
+		if _, found := files[name]; !found {
+			t.Errorf("%s wasn't rendered", name)
+		}
+	}
+
+	page := string(files["Simple.html"])
+	for _, expected := range []string{
+		"<li>Line 4: unresolved link to Missing <code>unresolved-link</code></li>",
+		"<li>Line 7: parameter a of add(int,int) is undocumented <code>undocumented-param</code></li>",
+		"<li>Line 7: add(int,int) returns int, but has no @return <code>missing-return</code></li>",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("page doesn't show %q", expected)
+		}
+	}
+}
+
+func TestRenderSiteErrors(t *testing.T) {
+	sources := map[string]string{
+		"Good.java":   "/**\n * Renders, see {@link Missing}\n */\npublic class Good {}\n",
+		"Broken.java": "/**\n * Fails to render\n */\npublic class Broken {}\n",
+		"Empty.java":  "/** Never closed, see {@link Missing}\npublic class Empty {}\n",
+	}
+
+	// The class template fails for Broken alone
+	directory := t.TempDir()
+	class := `{{ if eq .Class.Name "Broken" }}{{ index .Members 0 }}{{ end }}{{ range .Findings }}{{ .Rule }}{{ end }}`
+	if err := os.WriteFile(filepath.Join(directory, "class.html.tmpl"), []byte(class), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := make(chan *Document, len(sources))
+	for name, source := range sources {
+		docs <- ParseDocument(BeginScanningJavaCode(name, source), name)
+	}
+	close(docs)
+
+	files, err := RenderSite(&VisitorConfigOptions{TemplateDirectory: directory}, docs)
+
+	var list ErrorList
+	var fileErr *FileError
+	if !errors.As(err, &list) || len(list) != 1 || !errors.As(list[0], &fileErr) || fileErr.Path != "Broken.java" {
+		t.Fatalf("got %v, wanted an error with Broken.java alone", err)
+	}
+
+	// The rest of the site is still rendered, with the same findings as lint
+	if page := string(files["Good.html"]); page != "unresolved-link" {
+		t.Errorf("got %q for Good.html, wanted its unresolved link", page)
+	}
+
+	if _, found := files["index.html"]; !found {
+		t.Error("index.html wasn't rendered")
+	}
+}
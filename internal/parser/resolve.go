@@ -0,0 +1,312 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dburkart/javadoc2md/internal/logger"
+)
+
+// A LinkError describes a link target which couldn't be resolved, or which
+// could refer to more than one symbol.
+type LinkError struct {
+	Target  string
+	Matches []Symbol // Every symbol the target may refer to, if ambiguous
+}
+
+func (e *LinkError) Error() string {
+	if !e.Ambiguous() {
+		return "unresolved link to " + e.Target
+	}
+
+	var matches []string
+	for _, symbol := range e.Matches {
+		matches = append(matches, symbol.Location)
+	}
+
+	return "ambiguous link to " + e.Target + " (matches " + strings.Join(matches, ", ") + ")"
+}
+
+func (e *LinkError) Ambiguous() bool {
+	return len(e.Matches) > 1
+}
+
+// Code names the kind of problem, for lint findings and diagnostics.
+func (e *LinkError) Code() string {
+	if e.Ambiguous() {
+		return "ambiguous-link"
+	}
+	return "unresolved-link"
+}
+
+// Resolve returns the symbol which a link target in doc refers to. Names are
+// looked up the way javac would: the class itself and the classes nested in
+// it, then single-type imports, the class's own package, java.lang and
+// on-demand imports. As a last resort, the target is looked up as
+// written, which finds qualified names and unambiguous simple names.
+//
+// Methods whose parameter types differ from the target's only in how they're
+// written (i.e. "List" for "java.util.List<String>") are matched too. When a
+// target is ambiguous, the first matching symbol is returned along with a
+// LinkError.
+func (symbols SymbolMap) Resolve(doc *Document, target string) (Symbol, error) {
+	names := append(candidates(doc, target), "java.lang."+target, target)
+
+	for _, name := range names {
+		symbol, found := symbols.Lookup(name)
+		if !found {
+			continue
+		}
+
+		// A method named without its parameters may be any of its overloads
+		if symbol.Type == SYM_TYPE_METHOD && !strings.Contains(name, "(") {
+			if overloads := symbols.overloads(name); len(overloads) > 1 {
+				return symbol, &LinkError{Target: target, Matches: overloads}
+			}
+		}
+
+		return symbol, nil
+	}
+
+	if _, member, _ := strings.Cut(target, "#"); strings.Contains(member, "(") {
+		for _, name := range names {
+			var matches []Symbol
+			for _, overload := range symbols.overloads(name) {
+				if sameParameters(overload.QualifiedName, member) {
+					matches = append(matches, overload)
+				}
+			}
+
+			if len(matches) == 1 {
+				return matches[0], nil
+			} else if len(matches) > 1 {
+				return matches[0], &LinkError{Target: target, Matches: matches}
+			}
+		}
+	}
+
+	return Symbol{}, &LinkError{Target: target}
+}
+
+// overloads returns every method with the same class and name as the given
+// symbol name, i.e. "Foo#bar" or "com.example.Foo#bar(int)".
+func (symbols SymbolMap) overloads(name string) []Symbol {
+	name, _, _ = strings.Cut(name, "(")
+
+	seen := map[string]bool{}
+	var result []Symbol
+	for _, symbol := range symbols.methods[name] {
+		id := symbol.Package + "." + symbol.Location
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		result = append(result, symbol)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Location < result[j].Location
+	})
+
+	return result
+}
+
+// sameParameters returns whether two method references (i.e. "foo(int[])"
+// and "foo(int...)") have the same parameter types, once normalized.
+func sameParameters(a string, b string) bool {
+	_, a, _ = strings.Cut(strings.TrimSuffix(a, ")"), "(")
+	_, b, _ = strings.Cut(strings.TrimSuffix(b, ")"), "(")
+
+	aTypes, bTypes := splitParameters(a), splitParameters(b)
+	if len(aTypes) != len(bTypes) {
+		return false
+	}
+
+	for i := range aTypes {
+		if normalizeType(aTypes[i]) != normalizeType(bTypes[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// erasure removes the type arguments from a type, i.e. "Map<K, List<V>>"
+// becomes "Map".
+func erasure(t string) string {
+	var result strings.Builder
+	depth := 0
+
+	for _, c := range t {
+		switch {
+		case c == '<':
+			depth++
+		case c == '>':
+			depth--
+		case depth == 0:
+			result.WriteRune(c)
+		}
+	}
+
+	return result.String()
+}
+
+// normalizeType returns the form of a parameter type used to compare
+// methods: its erasure and simple name, with varargs written as an array.
+func normalizeType(t string) string {
+	t = strings.ReplaceAll(erasure(t), " ", "")
+	t = strings.Replace(t, "...", "[]", 1)
+
+	name := strings.TrimRight(t, "[]")
+	return name[strings.LastIndex(name, ".")+1:] + t[len(name):]
+}
+
+// candidates returns the qualified names which a link target in doc might
+// refer to, in the order javac would consider them. The well-known classes
+// in java.lang are included before on-demand imports; any others are left
+// to the caller.
+func candidates(doc *Document, target string) []string {
+	class, member, isMember := strings.Cut(target, "#")
+	if isMember {
+		member = "#" + member
+	}
+
+	// Qualified names start with a package, which by convention is lowercase
+	first, rest, isQualified := strings.Cut(class, ".")
+	if isQualified {
+		rest = "." + rest
+		if first == "" || !unicode.IsUpper(rune(first[0])) {
+			return nil
+		}
+	}
+
+	var result []string
+	local := doc.Package + "."
+
+	if len(doc.Blocks) > 0 {
+		outer := doc.Blocks[0].Name
+
+		if first == outer {
+			result = append(result, local+class+member)
+		}
+
+		// Members of nested classes are recorded as members of the outer
+		// class
+		for _, block := range doc.Blocks[1:] {
+			if block.Name != first || rest != "" || !isType(block.Type) {
+				continue
+			}
+
+			if isMember {
+				result = append(result, local+outer+member)
+			} else {
+				result = append(result, local+outer+"#"+first)
+			}
+		}
+	}
+
+	for _, imp := range doc.Imports {
+		if imp.OnDemand() || !strings.HasSuffix(imp.Name, "."+first) {
+			continue
+		}
+
+		if imp.Static && !isMember && rest == "" {
+			result = append(result, strings.TrimSuffix(imp.Owner(), ".")+"#"+first)
+		} else if !imp.Static {
+			result = append(result, imp.Name+rest+member)
+		}
+	}
+
+	result = append(result, local+class+member)
+
+	// Classes in java.lang come before on-demand imports. External libraries
+	// only list packages, so a class in java.lang would otherwise be found
+	// in every package imported on demand, i.e. String in java.util
+	if !isQualified && javaLangClasses[first] {
+		result = append(result, "java.lang."+class+member)
+	}
+
+	for _, imp := range doc.Imports {
+		if !imp.OnDemand() {
+			continue
+		}
+
+		if imp.Static && !isMember && rest == "" {
+			result = append(result, strings.TrimSuffix(imp.Owner(), ".")+"#"+first)
+		} else if !imp.Static {
+			result = append(result, imp.Owner()+class+member)
+		}
+	}
+
+	return result
+}
+
+func isType(t SymbolType) bool {
+	return t == SYM_TYPE_CLASS || t == SYM_TYPE_INTERFACE || t == SYM_TYPE_ENUM
+}
+
+// The LinkVisitor reports every link which can't be resolved, or which is
+// ambiguous. It must run after any visitor which adds symbols.
+type LinkVisitor struct {
+	Symbols SymbolMap
+}
+
+func (v *LinkVisitor) visit(doc *Document) error {
+	for _, link := range links(doc) {
+		if _, linkErr := v.Symbols.Resolve(doc, linkTarget(doc, link.Lexeme)); linkErr != nil {
+			logger.Report(logger.Diagnostic{
+				Level:   logger.LOG_LEVEL_WARN,
+				Code:    linkErr.(*LinkError).Code(),
+				File:    doc.Address,
+				Line:    link.Start.Line,
+				Column:  link.Start.Column,
+				Message: linkErr.Error(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// links returns the content of every link in doc, in order.
+func links(doc *Document) []Token {
+	var contents []Token
+
+	for _, block := range doc.Blocks {
+		texts := []Text{block.Text}
+		for _, key := range sortedKeys(block.Tags) {
+			texts = append(texts, block.Tags[key])
+		}
+		for _, key := range sortedKeys(block.Params) {
+			texts = append(texts, block.Params[key])
+		}
+
+		for _, text := range texts {
+			for i, token := range text {
+				if token.Type == TOK_JDOC_PARAM && isLink(token.Lexeme) && i+1 < len(text) {
+					contents = append(contents, text[i+1])
+				}
+			}
+		}
+	}
+
+	return contents
+}
+
+func sortedKeys(m map[string]Text) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
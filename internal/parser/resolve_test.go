@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImports(t *testing.T) {
+	input := `
+package com.example;
+
+// import com.example.commented.Out;
+import java.util.List;
+import java.util.concurrent.*;
+import static java.lang.Math.max;
+import static java.util.Collections.*;
+
+/**
+ * Imports things. Important things.
+ */
+public class Importer {
+	private String important;
+}`
+	doc := ParseDocument(BeginScanningJavaCode("Importer.java", input), "Importer.java")
+
+	expected := []Import{
+		{Name: "java.util.List"},
+		{Name: "java.util.concurrent.*"},
+		{Name: "java.lang.Math.max", Static: true},
+		{Name: "java.util.Collections.*", Static: true},
+	}
+	if !reflect.DeepEqual(doc.Imports, expected) {
+		t.Errorf("got %v, wanted %v", doc.Imports, expected)
+	}
+
+	if doc.Package != "com.example" || doc.Blocks[0].Name != "Importer" {
+		t.Errorf("got package %q and class %q", doc.Package, doc.Blocks[0].Name)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	sources := map[string]string{
+		"a/Node.java": `
+package com.example.a;
+
+/** A node. */
+public class Node {
+	/** Visits the node. */
+	public void visit();
+}`,
+		"b/Node.java": `
+package com.example.b;
+
+/** Another node. */
+public class Node {
+	/** Visits the other node. */
+	public void visit();
+}`,
+		"b/Tree.java": `
+package com.example.b;
+
+/** A tree of nodes. */
+public class Tree {
+	/** A leaf. */
+	public static class Leaf {
+	}
+}`,
+		"c/Graph.java": `
+package com.example.c;
+
+import com.example.a.Node;
+import com.example.b.*;
+
+/** A graph. */
+public class Graph {
+}`,
+	}
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	docs := map[string]*Document{}
+	for name, source := range sources {
+		docs[name] = ParseDocument(BeginScanningJavaCode(name, source), name)
+		symbolVisitor.visit(docs[name])
+	}
+	symbols := symbolVisitor.Symbols
+
+	tests := []struct {
+		doc      string
+		target   string
+		expected string // Package of the resolved symbol
+	}{
+		{"c/Graph.java", "Node", "com.example.a"},         // Single-type import
+		{"c/Graph.java", "Node#visit()", "com.example.a"}, // Single-type import
+		{"c/Graph.java", "Tree", "com.example.b"},         // On-demand import
+		{"b/Tree.java", "Node", "com.example.b"},          // Same package
+		{"b/Tree.java", "Leaf", "com.example.b"},          // Nested
+		{"a/Node.java", "#visit()", "com.example.a"},      // Same class
+		{"a/Node.java", "com.example.b.Node", "com.example.b"},
+	}
+
+	for _, test := range tests {
+		doc := docs[test.doc]
+		symbol, err := symbols.Resolve(doc, linkTarget(doc, test.target))
+		if err != nil {
+			t.Errorf("%s: %s", test.doc, err)
+		} else if symbol.Package != test.expected {
+			t.Errorf("%s: %s resolved to package %q, wanted %q", test.doc, test.target, symbol.Package, test.expected)
+		}
+	}
+
+	// Without an import, a simple name shared by two packages is ambiguous
+	if symbol, found := symbols.Lookup("Node"); found {
+		t.Errorf("expected Node to be ambiguous, found %v", symbol)
+	}
+}
+
+func TestResolveOverloads(t *testing.T) {
+	input := `
+package com.example;
+
+/** Overloads. */
+public class Overloads {
+	/** Takes a list. */
+	public void take(java.util.List<String> items);
+
+	/** Takes a map. */
+	public void take(Map<String, List<Integer>> items, int... counts);
+
+	/** Takes one kind of list. */
+	public void pick(com.a.List items);
+
+	/** Takes another kind of list. */
+	public void pick(com.b.List items);
+}`
+	doc := ParseDocument(BeginScanningJavaCode("Overloads.java", input), "Overloads.java")
+
+	expectedArguments := []ArgPair{{Type: "Map<String,List<Integer>>", Name: "items"}, {Type: "int...", Name: "counts"}}
+	if !reflect.DeepEqual(doc.Blocks[2].Arguments, expectedArguments) {
+		t.Errorf("got arguments %v, wanted %v", doc.Blocks[2].Arguments, expectedArguments)
+	}
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(doc)
+	symbols := symbolVisitor.Symbols
+
+	tests := []struct {
+		target    string
+		expected  string // Location of the resolved symbol
+		ambiguous bool
+	}{
+		{"#take(List)", "Overloads#take(java.util.List)", false},
+		{"#take(java.util.List<String> list)", "Overloads#take(java.util.List)", false},
+		{"#take(Map, int[])", "Overloads#take(Map,int...)", false},
+		{"#take", "Overloads#take(java.util.List)", true}, // The first declared
+		{"#pick(com.b.List)", "Overloads#pick(com.b.List)", false},
+		{"#pick(List)", "Overloads#pick(com.a.List)", true},
+	}
+
+	for _, test := range tests {
+		symbol, err := symbols.Resolve(doc, linkTarget(doc, test.target))
+		if symbol.Location != test.expected {
+			t.Errorf("%s: resolved to %q, wanted %q", test.target, symbol.Location, test.expected)
+		}
+
+		linkErr, _ := err.(*LinkError)
+		if test.ambiguous != (linkErr != nil && linkErr.Ambiguous()) {
+			t.Errorf("%s: got error %v", test.target, err)
+		}
+	}
+
+	if _, err := symbols.Resolve(doc, linkTarget(doc, "#take(String)")); err == nil {
+		t.Errorf("expected #take(String) not to resolve")
+	}
+}
+
+func TestOverloadIndex(t *testing.T) {
+	inputs := map[string]string{
+		"a/Foo.java": "package com.a;\n/** Foo */\npublic class Foo {\n/** bar */\npublic void bar(int x);\n/** bar */\npublic void bar(String s);\n}",
+		"b/Foo.java": "package com.b;\n/** Foo */\npublic class Foo {\n/** bar */\npublic void bar(int x);\n}",
+	}
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	for _, path := range []string{"a/Foo.java", "b/Foo.java"} {
+		symbolVisitor.visit(ParseDocument(BeginScanningJavaCode(path, inputs[path]), path))
+	}
+	symbols := symbolVisitor.Symbols
+
+	tests := []struct {
+		name     string
+		expected []string // Locations of the overloads
+	}{
+		{"com.a.Foo#bar", []string{"Foo#bar(String)", "Foo#bar(int)"}},
+		{"com.b.Foo#bar(long)", []string{"Foo#bar(int)"}},
+		// Foo#bar(int) is declared in both packages, so only its qualified
+		// names are recorded
+		{"Foo#bar", []string{"Foo#bar(String)"}},
+		{"Foo#baz", nil},
+	}
+
+	for _, test := range tests {
+		var locations []string
+		for _, overload := range symbols.overloads(test.name) {
+			locations = append(locations, overload.Location)
+		}
+
+		if !reflect.DeepEqual(locations, test.expected) {
+			t.Errorf("%s: got overloads %v, wanted %v", test.name, locations, test.expected)
+		}
+	}
+}
+
+func TestLinkLabels(t *testing.T) {
+	tests := []struct {
+		content   string
+		symbol    Symbol
+		reference string
+		label     string
+	}{
+		{"Foo", Symbol{}, "Foo", "Foo"},
+		{"com.example.Foo", Symbol{}, "com.example.Foo", "Foo"},
+		{"java.util.Map.Entry", Symbol{}, "java.util.Map.Entry", "Map.Entry"},
+		{"#bar", Symbol{Type: SYM_TYPE_METHOD, QualifiedName: "bar(java.util.List,int...)"}, "#bar", "bar(List, int...)"},
+		{"Foo#bar(int, String name) the bar method", Symbol{}, "Foo#bar(int, String name)", "the bar method"},
+		{"Foo#bar(int, String name)", Symbol{}, "Foo#bar(int, String name)", "Foo.bar(int, String)"},
+		{"Foo#CONSTANT   a  constant ", Symbol{}, "Foo#CONSTANT", "a constant"},
+	}
+
+	for _, test := range tests {
+		reference, label := splitLink(test.content)
+		if label == "" {
+			label = linkLabel(reference, test.symbol)
+		}
+
+		if reference != test.reference || label != test.label {
+			t.Errorf("%q: got reference %q and label %q, wanted %q and %q", test.content, reference, label, test.reference, test.label)
+		}
+	}
+}
+
+func TestCollectSymbolsOrder(t *testing.T) {
+	first := "package com.example;\n\n/** A class */\npublic class Widget {\n}"
+	second := "package com.example;\n\n/** An interface */\npublic interface Widget {\n}"
+
+	// Whichever order documents arrive in, the colliding symbol comes from the
+	// same one
+	for _, order := range [][]string{{"a/Widget.java", "b/Widget.java"}, {"b/Widget.java", "a/Widget.java"}} {
+		docs := make(chan *Document, 2)
+		for _, path := range order {
+			source := first
+			if path == "b/Widget.java" {
+				source = second
+			}
+			docs <- ParseDocument(BeginScanningJavaCode(path, source), path)
+		}
+		close(docs)
+
+		documents, symbols := collectSymbols(&VisitorConfigOptions{}, docs)
+		if documents[0].Address != "a/Widget.java" {
+			t.Errorf("got %s first, wanted a/Widget.java", documents[0].Address)
+		}
+
+		if symbol, _ := symbols.Lookup("com.example.Widget"); symbol.Type != SYM_TYPE_INTERFACE {
+			t.Errorf("got %s for com.example.Widget, wanted interface", symbol.Type)
+		}
+	}
+}
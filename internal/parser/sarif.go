@@ -0,0 +1,194 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// A LintRule is a kind of problem lint reports.
+type LintRule struct {
+	ID          string
+	Description string
+}
+
+// LintRules lists every rule lint checks, in the order they're documented.
+var LintRules = []LintRule{
+	{"unresolved-link", "{@link} targets which can't be found"},
+	{"ambiguous-link", "{@link} targets which match more than one overload"},
+	{"unresolved-see", "@see references which can't be found"},
+	{"undocumented-param", "Parameters without a @param tag"},
+	{"unknown-param", "@param tags which don't name a parameter"},
+	{"missing-return", "Methods which return a value, but have no @return tag"},
+	{"undocumented", "Public members with an empty comment"},
+	{"malformed-html", "HTML elements which are never closed, or closed out of order"},
+	{"unclosed-tag", "Inline tags, like {@code, which are missing their }"},
+}
+
+// The parts of the SARIF 2.1.0 format which describe lint findings. See
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactURI `json:"originalUriBaseIds,omitempty"`
+	ColumnKind         string                      `json:"columnKind"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactURI `json:"artifactLocation"`
+	Region           sarifRegion      `json:"region"`
+}
+
+type sarifArtifactURI struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// WriteLintSARIF writes findings as a SARIF 2.1.0 log, which code review
+// tools can show as annotations. Files under root are given relative to it,
+// as %SRCROOT%, and any others by their absolute path.
+func WriteLintSARIF(w io.Writer, findings []Finding, root string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "javadoc2md",
+			InformationURI: "https://github.com/dburkart/javadoc2md",
+		}},
+		OriginalURIBaseIDs: map[string]sarifArtifactURI{
+			"%SRCROOT%": {URI: strings.TrimSuffix(fileURI(absRoot), "/") + "/"},
+		},
+		// Positions count characters, rather than the default UTF-16 code units
+		ColumnKind: "unicodeCodePoints",
+		Results:    []sarifResult{},
+	}
+
+	ruleIndex := make(map[string]int)
+	for i, rule := range LintRules {
+		ruleIndex[rule.ID] = i
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{rule.Description},
+			DefaultConfiguration: sarifConfiguration{"warning"},
+		})
+	}
+
+	for _, finding := range findings {
+		location, err := artifactLocation(finding.File, absRoot)
+		if err != nil {
+			return err
+		}
+
+		region := sarifRegion{StartLine: finding.Line, StartColumn: finding.Column}
+
+		// SARIF's end line is the last line in the region, so a region ending
+		// at the start of a line ends on the line before
+		if finding.EndLine > finding.Line || finding.EndLine == finding.Line && finding.EndColumn > finding.Column {
+			region.EndLine, region.EndColumn = finding.EndLine, finding.EndColumn
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    finding.Rule,
+			RuleIndex: ruleIndex[finding.Rule],
+			Level:     "warning",
+			Message:   sarifMessage{finding.Message},
+			Locations: []sarifLocation{{sarifPhysicalLocation{ArtifactLocation: location, Region: region}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// artifactLocation returns the location of file, relative to root if it's
+// inside of it.
+func artifactLocation(file string, root string) (sarifArtifactURI, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return sarifArtifactURI{}, err
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return sarifArtifactURI{URI: fileURI(path)}, nil
+	}
+
+	return sarifArtifactURI{URI: (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath(), URIBaseID: "%SRCROOT%"}, nil
+}
+
+// fileURI returns the file:// URI of an absolute path.
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths, like C:/src, need a leading slash
+		path = "/" + path
+	}
+
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
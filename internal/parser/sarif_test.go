@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteLintSARIF(t *testing.T) {
+	input := `
+/**
+ * See {@link Missing}, and {@code broken
+ */
+public class Test {
+}`
+
+	findings := lint(t, input)
+
+	var output bytes.Buffer
+	if err := WriteLintSARIF(&output, findings, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(output.Bytes(), &log); err != nil {
+		t.Fatalf("could not read back %s: %v", output.String(), err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("got version %q with %d runs, wanted version 2.1.0 with 1 run", log.Version, len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(LintRules) {
+		t.Errorf("got %d rules, wanted %d", len(run.Tool.Driver.Rules), len(LintRules))
+	}
+
+	expected := []struct {
+		rule   string
+		region sarifRegion
+	}{
+		{"unresolved-link", sarifRegion{3, 15, 3, 22}},
+		{"unclosed-tag", sarifRegion{3, 30, 3, 35}},
+	}
+
+	if len(run.Results) != len(expected) {
+		t.Fatalf("got %d results %+v, wanted %d", len(run.Results), run.Results, len(expected))
+	}
+
+	for i, result := range run.Results {
+		if result.RuleID != expected[i].rule || run.Tool.Driver.Rules[result.RuleIndex].ID != result.RuleID {
+			t.Errorf("got rule %q at index %d, wanted %q", result.RuleID, result.RuleIndex, expected[i].rule)
+		}
+
+		location := result.Locations[0].PhysicalLocation
+		if location.ArtifactLocation != (sarifArtifactURI{URI: "Test.java", URIBaseID: "%SRCROOT%"}) {
+			t.Errorf("got location %+v, wanted Test.java under %%SRCROOT%%", location.ArtifactLocation)
+		}
+
+		if location.Region != expected[i].region {
+			t.Errorf("got region %+v for %s, wanted %+v", location.Region, result.RuleID, expected[i].region)
+		}
+	}
+}
+
+func TestArtifactLocation(t *testing.T) {
+	root, err := filepath.Abs("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inside, err := artifactLocation(filepath.Join("src", "com", "example", "Foo Bar.java"), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inside.URI != "com/example/Foo%20Bar.java" || inside.URIBaseID != "%SRCROOT%" {
+		t.Errorf("got %+v, wanted com/example/Foo%%20Bar.java under %%SRCROOT%%", inside)
+	}
+
+	outside, err := artifactLocation("Other.java", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outside.URI != fileURI(filepath.Join(filepath.Dir(root), "Other.java")) || outside.URIBaseID != "" {
+		t.Errorf("got %+v, wanted an absolute file URI", outside)
+	}
+}
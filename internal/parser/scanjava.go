@@ -11,46 +11,50 @@ import (
 	"unicode"
 )
 
+// ScanBegin scans the code between declarations. Where declarations may
+// appear, at the top of the file or directly inside a type, anything besides
+// a comment starts one, while method bodies and initializers are skipped
+// over entirely.
 func ScanBegin(scanner *Scanner) ScanFn {
-	scanner.SkipWhitespace()
-	inComment := false
-
 	for {
-		if inComment {
-			if strings.HasPrefix(scanner.InputToEnd(), "*/") {
-				scanner.Pos += 2
-				scanner.Start = scanner.Pos
-				inComment = false
-				continue
-			}
-
-			goto advance
-		}
+		scanner.SkipWhitespace()
+		input := scanner.InputToEnd()
+		ch := scanner.Peek()
 
-		// First, check if a JavaDoc is beginning
-		if strings.HasPrefix(scanner.InputToEnd(), "/**") {
+		switch {
+		case ch == EOF:
+			scanner.Emit(TOK_EOF)
+			return nil
+		case strings.HasPrefix(input, "/**") && !strings.HasPrefix(input, "/**/") && scanner.atMemberLevel():
 			return ScanJavadocStart
-		}
-
-		// Ignore anything in a comment
-		if strings.HasPrefix(scanner.InputToEnd(), "/*") {
-			inComment = true
-			continue
-		}
-
-		// Check for a package name
-		if strings.HasPrefix(scanner.InputToEnd(), "package") {
+		case scanner.skipComment():
+		case ch == '{':
+			// Initializers, and the bodies of enum constants
+			scanner.Next()
+			scanner.openScope(scopeCode)
+		case ch == '}':
+			scanner.Next()
+			scanner.closeScope()
+		case !scanner.atMemberLevel():
+			if !scanner.skipLiteral() {
+				scanner.Next()
+			}
+		case ch == ';' || ch == ',':
+			scanner.Next()
+			if ch == ';' {
+				scanner.endEnumConstants()
+			}
+		case scanner.AtKeyword("package"):
 			return ScanPackageStatement
+		case scanner.AtKeyword("import"):
+			return ScanImportStatement
+		case scanner.inEnumConstants():
+			return ScanEnumConstant
+		default:
+			return ScanJavaLine
 		}
 
-	advance:
-		ch := scanner.Next()
 		scanner.Start = scanner.Pos
-
-		if ch == EOF {
-			scanner.Emit(TOK_EOF)
-			return nil
-		}
 	}
 }
 
@@ -60,13 +64,28 @@ func ScanPackageStatement(scanner *Scanner) ScanFn {
 	return ScanPackageName
 }
 
+func ScanImportStatement(scanner *Scanner) ScanFn {
+	scanner.Pos += len("import")
+	scanner.Emit(TOK_JAVA_KEYWORD)
+	scanner.SkipWhitespace()
+
+	if scanner.AtKeyword("static") {
+		scanner.Pos += len("static")
+		scanner.Emit(TOK_JAVA_KEYWORD)
+	}
+
+	return ScanPackageName
+}
+
+// ScanPackageName scans the name in a package or import statement, which
+// for on-demand imports ends in ".*"
 func ScanPackageName(scanner *Scanner) ScanFn {
 	scanner.SkipWhitespace()
 	for {
 		ch := scanner.Peek()
 
 		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
-			(ch >= '0' && ch <= '9') || ch == '.' || ch == '_' {
+			(ch >= '0' && ch <= '9') || ch == '.' || ch == '_' || ch == '$' || ch == '*' {
 			scanner.Inc()
 		} else {
 			scanner.Emit(TOK_JAVA_IDENTIFIER)
@@ -85,9 +104,66 @@ func ScanJavadocStart(scanner *Scanner) ScanFn {
 func ScanJavadocEnd(scanner *Scanner) ScanFn {
 	scanner.Pos += len("*/")
 	scanner.Emit(TOK_JDOC_END)
+	scanner.SkipWhitespace()
+
+	// A comment at the top of the file, such as a license, isn't followed by
+	// a declaration
+	if scanner.AtKeyword("package") || scanner.AtKeyword("import") {
+		return ScanBegin
+	}
+
+	if scanner.inEnumConstants() {
+		return ScanEnumConstant
+	}
+
 	return ScanJavaLine
 }
 
+// ScanEnumConstant scans one of the constants at the start of an enum. Any
+// arguments it's constructed with are skipped, as is its body, by ScanBegin.
+func ScanEnumConstant(scanner *Scanner) ScanFn {
+	scanner.SkipWhitespace()
+
+	for scanner.Peek() == '@' {
+		scanAnnotation(scanner)
+		scanner.SkipWhitespace()
+	}
+
+	if !isIdentifierRune(scanner.Peek()) {
+		return ScanBegin
+	}
+
+	for isIdentifierRune(scanner.Peek()) {
+		scanner.Next()
+	}
+	scanner.Emit(TOK_JAVA_IDENTIFIER)
+	scanner.SkipWhitespace()
+
+	if scanner.Peek() == '(' {
+		depth := 0
+		for {
+			if scanner.skipLiteral() {
+				continue
+			}
+
+			ch := scanner.Next()
+			if ch == '(' {
+				depth++
+			} else if ch == ')' {
+				depth--
+			}
+
+			if depth == 0 || ch == EOF {
+				break
+			}
+		}
+		scanner.Start = scanner.Pos
+	}
+
+	scanner.Emit(TOK_JAVA_DECL_END)
+	return ScanBegin
+}
+
 func ScanJavadoc(scanner *Scanner) ScanFn {
 	scanner.SkipJavadocFiller()
 
@@ -107,6 +183,15 @@ func ScanJavadocLine(scanner *Scanner) ScanFn {
 	for {
 		ch := scanner.Peek()
 
+		// The comment is never closed
+		if ch == EOF {
+			if scanner.Pos > scanner.Start {
+				scanner.Emit(TOK_JDOC_LINE)
+			}
+			scanner.Emit(TOK_EOF)
+			return nil
+		}
+
 		if ch == '*' {
 			scanner.Inc()
 
@@ -158,7 +243,7 @@ func ScanJSXTag(scanner *Scanner) ScanFn {
 	for {
 		c := scanner.Next()
 
-		if c == '\n' {
+		if c == '\n' || c == EOF {
 			scanner.Pos = position + 1
 			return ScanJavadoc
 		}
@@ -178,7 +263,7 @@ func ScanJavadocTag(scanner *Scanner) ScanFn {
 	for {
 		ch := scanner.Next()
 
-		if unicode.IsSpace(ch) {
+		if unicode.IsSpace(ch) || ch == EOF {
 			scanner.Rewind()
 			lexeme := scanner.Input[scanner.Start:scanner.Pos]
 			scanner.Emit(TOK_JDOC_TAG)
@@ -193,12 +278,12 @@ func ScanJavadocTag(scanner *Scanner) ScanFn {
 
 removeKey:
 	scanner.Inc()
-	scanner.Start += 1
+	scanner.Start = scanner.Pos
 
 	for {
 		ch := scanner.Next()
 
-		if unicode.IsSpace(ch) {
+		if unicode.IsSpace(ch) || ch == EOF {
 			scanner.Rewind()
 			scanner.Emit(TOK_JDOC_LINE)
 			return ScanJavadocLine
@@ -220,6 +305,23 @@ func ScanJavadocParam(scanner *Scanner) ScanFn {
 	insideParam := false
 
 	for {
+		// An inline tag which is never closed ends with the comment, and is
+		// marked by an empty TOK_JDOC_PARAM_END
+		if strings.HasPrefix(scanner.InputToEnd(), "*/") || scanner.Peek() == EOF {
+			if insideParam {
+				scanner.Emit(TOK_JDOC_PARAM)
+			} else if scanner.Pos > scanner.Start {
+				scanner.Emit(TOK_JDOC_LINE)
+			}
+			scanner.Emit(TOK_JDOC_PARAM_END)
+
+			if scanner.Peek() == EOF {
+				scanner.Emit(TOK_EOF)
+				return nil
+			}
+			return ScanJavadocEnd
+		}
+
 		ch := scanner.Next()
 
 		if ch == '}' {
@@ -254,50 +356,77 @@ func ScanJavadocParam(scanner *Scanner) ScanFn {
 			}
 
 			scanner.Inc()
-			scanner.Start += 1
+			scanner.Start = scanner.Pos
 
 			// Eat whitespace / "*"
-			for {
+			for !strings.HasPrefix(scanner.InputToEnd(), "*/") {
 				ch = scanner.Next()
 
 				if unicode.IsSpace(ch) || ch == '*' {
-					scanner.Start += 1
+					scanner.Start = scanner.Pos
 				} else {
+					scanner.Rewind()
 					break
 				}
 			}
 
-			scanner.Rewind()
+			continue
 		}
 
 		if unicode.IsSpace(ch) && insideParam {
 			scanner.Rewind()
 			scanner.Emit(TOK_JDOC_PARAM)
 			scanner.Inc()
-			scanner.Start += 1
+			scanner.Start = scanner.Pos
 			insideParam = false
 		}
 	}
 }
 
+// ScanJavaLine scans a single declaration, up to the semicolon or brace
+// which ends it. If it declares a type, the scanner looks for declarations in
+// its body; otherwise, the body is skipped.
 func ScanJavaLine(scanner *Scanner) ScanFn {
+	// What the body following the declaration is, and whether we've reached
+	// an initializer, in which a type keyword doesn't declare anything
+	body, initializer := scopeCode, false
+	previous := ""
+
 	for {
 		scanner.SkipWhitespace()
 
 		ch := scanner.Peek()
 
-		if ch == ';' {
+		if ch == EOF || strings.HasPrefix(scanner.InputToEnd(), "/**") {
 			return ScanBegin
 		}
 
-		if ch == '{' || ch == '}' {
+		if ch == ';' || ch == '{' {
+			scanner.Next()
+			scanner.Emit(TOK_JAVA_DECL_END)
+
+			if ch == '{' {
+				scanner.openScope(body)
+			}
 			return ScanBegin
 		}
 
-		if strings.HasPrefix(scanner.InputToEnd(), "/**") {
+		// The end of an enclosing type; the declaration is missing its end
+		if ch == '}' {
+			scanner.Emit(TOK_JAVA_DECL_END)
 			return ScanBegin
 		}
 
+		if scanner.skipComment() {
+			scanner.Start = scanner.Pos
+			continue
+		}
+
+		// Type keywords declare a type, unless they're part of an
+		// initializer or a class literal, i.e. "String.class"
+		declaresType := !initializer && previous != "."
+		previous = string(ch)
+
 		switch ch {
 		case '.':
 			scanner.Inc()
@@ -311,6 +440,7 @@ func ScanJavaLine(scanner *Scanner) ScanFn {
 			if strings.HasPrefix(scanner.InputToEnd(), ">>") {
 				scanner.Pos += 2
 				scanner.Emit(TOK_JAVA_OPERATOR)
+				continue
 			}
 
 			scanner.Inc()
@@ -320,6 +450,7 @@ func ScanJavaLine(scanner *Scanner) ScanFn {
 			if strings.HasPrefix(scanner.InputToEnd(), "<<") {
 				scanner.Pos += 2
 				scanner.Emit(TOK_JAVA_OPERATOR)
+				continue
 			}
 			scanner.Inc()
 			scanner.Emit(TOK_JAVA_OTHER)
@@ -355,38 +486,13 @@ func ScanJavaLine(scanner *Scanner) ScanFn {
 		case '=':
 			scanner.Inc()
 			scanner.Emit(TOK_JAVA_EQUAL)
+			initializer = true
 			continue
-		case '"':
-			scanner.Inc()
-			for {
-				ch := scanner.Next()
-
-				if ch == '"' {
-					scanner.Emit(TOK_JAVA_STRING)
-					break
-				}
-			}
+		case '"', '\'':
+			scanner.skipLiteral()
+			scanner.Emit(TOK_JAVA_STRING)
 			continue
 		case '/':
-			if strings.HasPrefix(scanner.InputToEnd(), "/*") {
-				scanner.Pos += 2
-				scanner.Emit(TOK_JAVA_COMMENT_O)
-				continue
-			}
-
-			if strings.HasPrefix(scanner.InputToEnd(), "//") {
-				for {
-					if scanner.Peek() == '\n' {
-						scanner.Inc()
-						scanner.Start = scanner.Pos
-						break
-					}
-
-					scanner.Inc()
-				}
-				continue
-			}
-
 			scanner.Pos += 1
 			scanner.Emit(TOK_JAVA_OPERATOR)
 			continue
@@ -414,67 +520,23 @@ func ScanJavaLine(scanner *Scanner) ScanFn {
 			scanner.Pos += 1
 			scanner.Emit(TOK_JAVA_OPERATOR)
 			continue
-		case 'c':
-			if strings.HasPrefix(scanner.InputToEnd(), "class") {
-				scanner.Pos += len("class")
-				scanner.Emit(TOK_JAVA_KEYWORD)
-				continue
-			}
-		case 'e':
-			if strings.HasPrefix(scanner.InputToEnd(), "enum") {
-				scanner.Pos += len("enum")
-				scanner.Emit(TOK_JAVA_KEYWORD)
-				continue
-			}
-
-			if strings.HasPrefix(scanner.InputToEnd(), "extends") {
-				scanner.Pos += len("extends")
-				scanner.Emit(TOK_JAVA_KEYWORD)
-				continue
-			}
-		case 'i':
-			if strings.HasPrefix(scanner.InputToEnd(), "interface") {
-				scanner.Pos += len("interface")
-				scanner.Emit(TOK_JAVA_KEYWORD)
-				continue
-			}
-		case 'p':
-			if strings.HasPrefix(scanner.InputToEnd(), "public") {
-				scanner.Pos += len("public")
-				scanner.Emit(TOK_JAVA_KEYWORD)
-				continue
+		case '@':
+			if scanAnnotation(scanner) == "@interface" && declaresType {
+				body = scopeType
 			}
+			continue
+		}
 
-			if strings.HasPrefix(scanner.InputToEnd(), "private") {
-				scanner.Pos += len("private")
-				scanner.Emit(TOK_JAVA_KEYWORD)
-				continue
-			}
-		case 's':
-			if strings.HasPrefix(scanner.InputToEnd(), "static") {
-				scanner.Pos += len("static")
-				scanner.Emit(TOK_JAVA_KEYWORD)
-				continue
+		if keyword := scanKeyword(scanner); keyword != "" {
+			switch {
+			case !declaresType:
+			case keyword == "class" || keyword == "interface":
+				body = scopeType
+			case keyword == "enum":
+				body = scopeEnumConstants
 			}
-		case '@':
-			braces := 0
-			for {
-				if unicode.IsSpace(ch) && braces == 0 {
-					scanner.Emit(TOK_JAVA_ANNOTATION)
-					break
-				}
-
-				if ch == '(' || ch == '{' {
-					braces += 1
-				}
 
-				if ch == ')' || ch == '}' {
-					braces -= 1
-				}
-
-				scanner.Inc()
-				ch = scanner.Peek()
-			}
+			previous = keyword
 			continue
 		}
 
@@ -496,31 +558,73 @@ func ScanJavaLine(scanner *Scanner) ScanFn {
 			continue
 		}
 
+		// Anything else we don't understand is passed along as it is
+		if !isIdentifierRune(ch) {
+			scanner.Next()
+			scanner.Emit(TOK_JAVA_OTHER)
+			continue
+		}
+
 		// Pull characters off until we have an identifier
-		for {
-			if unicode.IsSpace(ch) {
-				scanner.Emit(TOK_JAVA_IDENTIFIER)
-				break
-			}
+		for isIdentifierRune(scanner.Peek()) {
+			scanner.Next()
+		}
+		scanner.Emit(TOK_JAVA_IDENTIFIER)
+	}
+}
 
-			if (ch < 'a' || ch > 'z') && (ch < 'A' || ch > 'Z') &&
-				(ch < '0' || ch > '9') && ch != '_' && ch != '$' {
-				scanner.Emit(TOK_JAVA_IDENTIFIER)
-				break
-			}
+var javaKeywords = []string{"class", "enum", "extends", "interface", "private", "protected", "public", "static"}
 
-			scanner.Inc()
-			ch = scanner.Peek()
+// scanKeyword emits the keyword at the current position, if there is one,
+// and returns it.
+func scanKeyword(scanner *Scanner) string {
+	for _, keyword := range javaKeywords {
+		if scanner.AtKeyword(keyword) {
+			scanner.Pos += len(keyword)
+			scanner.Emit(TOK_JAVA_KEYWORD)
+			return keyword
 		}
 	}
+
+	return ""
+}
+
+// scanAnnotation emits the annotation at the current position, along with
+// its arguments, and returns it.
+func scanAnnotation(scanner *Scanner) string {
+	braces := 0
+	for {
+		ch := scanner.Peek()
+
+		if ch == EOF || (unicode.IsSpace(ch) && braces == 0) {
+			break
+		}
+
+		if scanner.skipLiteral() {
+			continue
+		}
+
+		if ch == '(' || ch == '{' {
+			braces += 1
+		}
+
+		if ch == ')' || ch == '}' {
+			braces -= 1
+		}
+
+		scanner.Next()
+	}
+
+	lexeme := scanner.Input[scanner.Start:scanner.Pos]
+	scanner.Emit(TOK_JAVA_ANNOTATION)
+	return lexeme
 }
 
 func BeginScanningJavaCode(name, input string) *Scanner {
 	s := &Scanner{
-		Name:   name,
-		Input:  input,
-		State:  ScanBegin,
-		Tokens: make(chan Token, 3),
+		Name:  name,
+		Input: input,
+		State: ScanBegin,
 	}
 
 	return s
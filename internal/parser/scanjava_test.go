@@ -6,14 +6,16 @@
 
 package parser
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func SetupWithState(input string, state ScanFn) *Scanner {
 	s := &Scanner{
-		Name:   "Test",
-		Input:  input,
-		State:  state,
-		Tokens: make(chan Token, 3),
+		Name:  "Test",
+		Input: input,
+		State: state,
 	}
 
 	return s
@@ -26,7 +28,7 @@ func TestScanJavadocStart(t *testing.T) {
 	s := SetupWithState(input, ScanJavadocStart)
 
 	s.State(s)
-	token := <-s.Tokens
+	token := s.NextToken()
 
 	if token.Type != TOK_JDOC_START {
 		t.Errorf("got %q, wanted %q", token.Type, TOK_JDOC_START)
@@ -39,7 +41,7 @@ func TestScanJavadocEnd(t *testing.T) {
 	s := SetupWithState(input, ScanJavadocEnd)
 
 	s.State(s)
-	token := <-s.Tokens
+	token := s.NextToken()
 
 	if token.Type != TOK_JDOC_END {
 		t.Errorf("got %q, wanted %q", token.Type, TOK_JDOC_END)
@@ -53,7 +55,7 @@ func TestScanJavadocLine(t *testing.T) {
 	s := SetupWithState(input, ScanJavadocLine)
 
 	s.State(s)
-	token := <-s.Tokens
+	token := s.NextToken()
 
 	if token.Type != TOK_JDOC_LINE {
 		t.Errorf("got %q, wanted %q", token.Type, TOK_JDOC_LINE)
@@ -68,7 +70,7 @@ func TestScanJavadocTag(t *testing.T) {
 	s := SetupWithState("@tag and other stuff", ScanJavadocTag)
 
 	s.State(s)
-	token := <-s.Tokens
+	token := s.NextToken()
 
 	if token.Type != TOK_JDOC_TAG {
 		t.Errorf("got %q, wanted %q", token.Type, TOK_JDOC_TAG)
@@ -83,7 +85,7 @@ func TestScanJavadocSingleLineComment(t *testing.T) {
 	s := SetupWithState("/** Read data from underlyingInputStream to readAheadBuffer asynchronously. */", ScanJavadocStart)
 
 	s.State = s.State(s)
-	token := <-s.Tokens
+	token := s.NextToken()
 
 	if token.Type != TOK_JDOC_START {
 		t.Errorf("got %q, wanted %q", token.Type, TOK_JDOC_START)
@@ -91,15 +93,55 @@ func TestScanJavadocSingleLineComment(t *testing.T) {
 
 	s.State = s.State(s)
 	s.State = s.State(s)
-	token = <-s.Tokens
+	token = s.NextToken()
 
 	if token.Type != TOK_JDOC_LINE {
 		t.Errorf("got %q, wanted %q", token.Type, TOK_JDOC_LINE)
 	}
 
 	s.State = s.State(s)
-	token = <-s.Tokens
+	token = s.NextToken()
 	if token.Type != TOK_JDOC_END {
 		t.Errorf("got %q, wanted %q", token.Type, TOK_JDOC_END)
 	}
 }
+
+func TestScanMalformedJava(t *testing.T) {
+	inputs := []string{
+		"public class Broken { # ~ \\ }",
+		"public class Broken { String s = \"never closed",
+		"public class Broken { char c = '",
+		"public class Broken { /* never closed",
+		"@Annotation(value = \"x\"",
+		"public class Broken {",
+		"/** <a",
+		"/** @param",
+		"/** @param x",
+		"/** {@link",
+	}
+
+	for _, input := range inputs {
+		done := make(chan *Document)
+		go func(input string) {
+			done <- ParseDocument(BeginScanningJavaCode("Test", input), "Broken.java")
+		}(input)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("scanning %q never finished", input)
+		}
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	source := generateClass(1000)
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		s := BeginScanningJavaCode("Benchmark", source)
+		for s.NextToken().Type != TOK_EOF {
+		}
+	}
+}
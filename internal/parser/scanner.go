@@ -7,41 +7,172 @@
 package parser
 
 import (
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
 type Scanner struct {
-	Name   string
-	Input  string
-	Tokens chan Token
-	State  ScanFn
+	Name  string
+	Input string
+	State ScanFn
+
+	// Tokens which have been emitted, but not yet returned by NextToken,
+	// along with where each was found
+	pending   []Token
+	contexts  []TokenContext
+	next      int
+	lastFound TokenContext
 
 	Start     int
 	Pos       int
 	RuneWidth int
+
+	// The position of the last token emitted, from which the next one's is
+	// counted
+	last Position
+
+	// The kind of each block enclosing the current position, innermost last
+	scopes []scope
 }
 
 type ScanFn func(*Scanner) ScanFn
 
+// A TokenContext is where a token was found: how many types enclose it, and
+// whether it's in the list of constants at the start of an enum.
+type TokenContext struct {
+	Depth        int
+	EnumConstant bool
+}
+
+// A scope is the kind of block the scanner is in, which tells it whether to
+// look for declarations, or skip over code.
+type scope int
+
+const (
+	scopeCode          scope = iota // A method body, initializer, or anything else
+	scopeType                       // The body of a class or interface
+	scopeEnumConstants              // The body of an enum, before its constants end
+)
+
+func (this *Scanner) openScope(kind scope) {
+	this.scopes = append(this.scopes, kind)
+}
+
+func (this *Scanner) closeScope() {
+	if len(this.scopes) > 0 {
+		this.scopes = this.scopes[:len(this.scopes)-1]
+	}
+}
+
+// atMemberLevel returns whether declarations may appear at the current
+// position: at the top of the file, or directly inside a type.
+func (this *Scanner) atMemberLevel() bool {
+	return len(this.scopes) == 0 || this.scopes[len(this.scopes)-1] != scopeCode
+}
+
+// inEnumConstants returns whether the scanner is in the list of constants
+// at the start of an enum.
+func (this *Scanner) inEnumConstants() bool {
+	return len(this.scopes) > 0 && this.scopes[len(this.scopes)-1] == scopeEnumConstants
+}
+
+// endEnumConstants marks the end of an enum's constants, after which its
+// body is like any other type's.
+func (this *Scanner) endEnumConstants() {
+	if this.inEnumConstants() {
+		this.scopes[len(this.scopes)-1] = scopeType
+	}
+}
+
 func (this *Scanner) Emit(tokenType TokenType) {
-	this.Tokens <- Token{Type: tokenType, Lexeme: this.Input[this.Start:this.Pos]}
+	start := this.position(this.Start)
+	end := this.position(this.Pos)
+
+	this.pending = append(this.pending, Token{Type: tokenType, Lexeme: this.Input[this.Start:this.Pos], Range: Range{start, end}})
+	this.contexts = append(this.contexts, TokenContext{Depth: len(this.scopes), EnumConstant: this.inEnumConstants()})
 	this.Start = this.Pos
 }
 
-func (this *Scanner) Inc() {
-	this.Pos++
-	if this.Pos >= utf8.RuneCountInString(this.Input) {
-		this.Emit(TOK_EOF)
+// NextToken returns the next token in the input, running the scanner's state
+// functions until one is emitted. Once the input is exhausted, it returns
+// TOK_EOF.
+func (this *Scanner) NextToken() Token {
+	for this.next == len(this.pending) {
+		this.pending = this.pending[:0]
+		this.contexts = this.contexts[:0]
+		this.next = 0
+
+		if this.State == nil {
+			end := this.position(len(this.Input))
+			return Token{Type: TOK_EOF, Range: Range{end, end}}
+		}
+
+		this.State = this.State(this)
 	}
+
+	t := this.pending[this.next]
+	this.lastFound = this.contexts[this.next]
+	this.next++
+	return t
+}
+
+// Context returns where the token last returned by NextToken was found.
+// Declarations are only scanned directly inside types, so its depth is the
+// number of types enclosing it.
+func (this *Scanner) Context() TokenContext {
+	return this.lastFound
 }
 
+// position returns the line and column of the given byte offset. Tokens are
+// emitted in order, so only the input since the last one needs counting.
+func (this *Scanner) position(offset int) Position {
+	if offset > len(this.Input) {
+		offset = len(this.Input)
+	}
+
+	if this.last.Line == 0 || offset < this.last.Offset {
+		this.last = Position{Offset: 0, Line: 1, Column: 1}
+	}
+
+	p := this.last
+	for _, ch := range this.Input[p.Offset:offset] {
+		if ch == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	p.Offset = offset
+
+	this.last = p
+	return p
+}
+
+// Inc moves past the character at the current position.
+func (this *Scanner) Inc() {
+	_, width := utf8.DecodeRuneInString(this.InputToEnd())
+	this.Pos += width
+}
+
+// Dec moves back over the character before the current position.
 func (this *Scanner) Dec() {
-	this.Pos--
+	_, width := utf8.DecodeLastRuneInString(this.Input[:this.Pos])
+	this.Pos -= width
+}
+
+// AtEnd returns whether the whole input has been read. Positions are byte
+// offsets, so this is a comparison against the input's length, rather than
+// a count of its characters.
+func (this *Scanner) AtEnd() bool {
+	return this.Pos >= len(this.Input)
 }
 
 func (this *Scanner) Next() rune {
-	if this.Pos >= utf8.RuneCountInString(this.Input) {
+	if this.AtEnd() {
+		// Nothing was read, so there's nothing to rewind
+		this.RuneWidth = 0
 		return EOF
 	}
 
@@ -66,22 +197,104 @@ func (this *Scanner) InputToEnd() string {
 	return this.Input[this.Pos:]
 }
 
+// AtKeyword returns whether the input at the current position is the given
+// keyword, rather than an identifier which merely starts or ends with it.
+func (this *Scanner) AtKeyword(keyword string) bool {
+	if !strings.HasPrefix(this.InputToEnd(), keyword) {
+		return false
+	}
+
+	before, _ := utf8.DecodeLastRuneInString(this.Input[:this.Pos])
+	after, _ := utf8.DecodeRuneInString(this.Input[this.Pos+len(keyword):])
+
+	return !isIdentifierRune(before) && !isIdentifierRune(after)
+}
+
+func isIdentifierRune(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' || ch == '$'
+}
+
 func (this *Scanner) SkipWhitespace() {
 	for {
 		ch := this.Next()
 
 		if !unicode.IsSpace(ch) {
-			this.Dec()
+			this.Rewind()
 			break
 		}
+	}
 
-		if ch == EOF {
-			this.Emit(TOK_EOF)
-			break
+	this.Start = this.Pos
+}
+
+// skipLiteral skips over a string, text block or character literal, any of
+// which may contain braces or comment markers. It returns whether there was
+// one to skip.
+func (this *Scanner) skipLiteral() bool {
+	input := this.InputToEnd()
+
+	if strings.HasPrefix(input, `"""`) {
+		i := 3
+		for i < len(input) && !strings.HasPrefix(input[i:], `"""`) {
+			if input[i] == '\\' {
+				i++
+			}
+			i++
+		}
+
+		this.Pos += clamp(i+3, len(input))
+		return true
+	}
+
+	if !strings.HasPrefix(input, `"`) && !strings.HasPrefix(input, "'") {
+		return false
+	}
+
+	quote := input[0]
+	i := 1
+	for i < len(input) && input[i] != quote && input[i] != '\n' {
+		if input[i] == '\\' {
+			i++
 		}
+		i++
 	}
 
-	this.Start = this.Pos
+	this.Pos += clamp(i+1, len(input))
+	return true
+}
+
+// skipComment skips over a block or line comment, returning whether there
+// was one to skip. The newline ending a line comment isn't skipped.
+func (this *Scanner) skipComment() bool {
+	input := this.InputToEnd()
+
+	switch {
+	case strings.HasPrefix(input, "/*"):
+		end := strings.Index(input[2:], "*/")
+		if end == -1 {
+			this.Pos += len(input)
+		} else {
+			this.Pos += end + 4
+		}
+	case strings.HasPrefix(input, "//"):
+		end := strings.IndexByte(input, '\n')
+		if end == -1 {
+			this.Pos += len(input)
+		} else {
+			this.Pos += end
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+func clamp(n int, max int) int {
+	if n > max {
+		return max
+	}
+	return n
 }
 
 // SkipJavadocFiller skips the "filler" characters at the beginning of
@@ -100,12 +313,7 @@ func (this *Scanner) SkipJavadocFiller() {
 		}
 
 		if ch == '\n' || !unicode.IsSpace(ch) {
-			this.Dec()
-			break
-		}
-
-		if ch == EOF {
-			this.Emit(TOK_EOF)
+			this.Rewind()
 			break
 		}
 	}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A SourceLinker builds links to the source of each block on a repository
+// host, from a template such as
+// "https://github.com/{repo}/blob/{rev}/{path}#L{line}".
+type SourceLinker struct {
+	Template string
+	Repo     string // Substituted for {repo}
+	Rev      string // Substituted for {rev}, i.e. a commit or branch
+	Root     string // Paths substituted for {path} are relative to this directory
+}
+
+// URL returns the link to the given line of a source file.
+func (s *SourceLinker) URL(file string, line int) string {
+	path := filepath.ToSlash(file)
+	if absolute, err := filepath.Abs(file); err == nil && s.Root != "" {
+		if relative, err := filepath.Rel(s.Root, absolute); err == nil && !strings.HasPrefix(relative, "..") {
+			path = filepath.ToSlash(relative)
+		}
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.NewReplacer(
+		"{repo}", s.Repo,
+		"{rev}", s.Rev,
+		"{path}", strings.Join(segments, "/"),
+		"{line}", strconv.Itoa(line),
+	).Replace(s.Template)
+}
+
+// FindRepository looks for the git repository containing directory, and
+// returns its root along with the commit currently checked out.
+func FindRepository(directory string) (root string, rev string, err error) {
+	root, err = filepath.Abs(directory)
+	if err != nil {
+		return "", "", err
+	}
+
+	for {
+		if _, statErr := os.Stat(filepath.Join(root, ".git")); statErr == nil {
+			break
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", "", errors.New("no git repository found above " + directory)
+		}
+		root = parent
+	}
+
+	gitDir, err := findGitDir(root)
+	if err != nil {
+		return "", "", err
+	}
+
+	rev, err = readHead(gitDir)
+	return root, rev, err
+}
+
+// findGitDir returns the directory git keeps its data in. It's usually .git,
+// but in a worktree or submodule, .git is a file pointing elsewhere.
+func findGitDir(root string) (string, error) {
+	gitDir := filepath.Join(root, ".git")
+
+	info, err := os.Stat(gitDir)
+	if err != nil || info.IsDir() {
+		return gitDir, err
+	}
+
+	content, err := os.ReadFile(gitDir)
+	if err != nil {
+		return "", err
+	}
+
+	target := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(target, "gitdir: ") {
+		return "", errors.New("unrecognized .git file in " + root)
+	}
+	target = strings.TrimPrefix(target, "gitdir: ")
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+
+	return target, nil
+}
+
+// readHead returns the commit HEAD refers to, following a symbolic ref to
+// either a loose or a packed ref.
+func readHead(gitDir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+
+	head := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(head, "ref: ") {
+		return head, nil
+	}
+	ref := strings.TrimPrefix(head, "ref: ")
+
+	// Refs are shared between worktrees, so look in the common directory too
+	dirs := []string{gitDir}
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(common))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		dirs = append(dirs, commonDir)
+	}
+
+	for _, dir := range dirs {
+		if loose, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+			return strings.TrimSpace(string(loose)), nil
+		}
+
+		packed, err := os.ReadFile(filepath.Join(dir, "packed-refs"))
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(packed), "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 && fields[1] == ref {
+				return fields[0], nil
+			}
+		}
+	}
+
+	return "", errors.New("could not resolve " + ref + " in " + gitDir)
+}
+
+// The SourceVisitor records a link to the source of every block, which is
+// rendered next to its heading.
+type SourceVisitor struct {
+	Linker *SourceLinker
+}
+
+func (v *SourceVisitor) visit(doc *Document) error {
+	for i := range doc.Blocks {
+		block := &doc.Blocks[i]
+
+		line := block.Declaration.Start.Line
+		if line == 0 {
+			line = block.Comment.Start.Line
+		}
+
+		block.SourceURL = v.Linker.URL(doc.Address, line)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSourceLinkerURL(t *testing.T) {
+	root := t.TempDir()
+	linker := SourceLinker{
+		Template: "https://git.example.com/{repo}/blob/{rev}/{path}#L{line}",
+		Repo:     "example/widgets",
+		Rev:      "main",
+		Root:     root,
+	}
+
+	url := linker.URL(filepath.Join(root, "src", "My Widget.java"), 12)
+	expected := "https://git.example.com/example/widgets/blob/main/src/My%20Widget.java#L12"
+	if url != expected {
+		t.Errorf("got %q, wanted %q", url, expected)
+	}
+}
+
+func writeGitFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindRepository(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "src", "main")
+	if err := os.MkdirAll(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	commit := strings.Repeat("a", 40)
+	writeGitFile(t, filepath.Join(root, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeGitFile(t, filepath.Join(root, ".git", "refs", "heads", "main"), commit+"\n")
+
+	found, rev, err := FindRepository(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != root || rev != commit {
+		t.Errorf("got %q at %q, wanted %q at %q", rev, found, commit, root)
+	}
+
+	// Refs may be packed, rather than each in their own file
+	packed := strings.Repeat("b", 40)
+	os.Remove(filepath.Join(root, ".git", "refs", "heads", "main"))
+	writeGitFile(t, filepath.Join(root, ".git", "packed-refs"), "# pack-refs with: peeled\n"+packed+" refs/heads/main\n")
+
+	if _, rev, _ = FindRepository(source); rev != packed {
+		t.Errorf("got %q from packed refs, wanted %q", rev, packed)
+	}
+
+	// A detached HEAD is the commit itself
+	writeGitFile(t, filepath.Join(root, ".git", "HEAD"), commit+"\n")
+
+	if _, rev, _ = FindRepository(source); rev != commit {
+		t.Errorf("got %q from a detached HEAD, wanted %q", rev, commit)
+	}
+}
+
+func TestSourceLinksRendered(t *testing.T) {
+	input := `
+/**
+ * A Simple Class
+ */
+public class SimpleClass {
+	/**
+	 * Adds two numbers together
+	 */
+	public int add(int a, int b);
+}`
+	s := BeginScanningJavaCode("Test", input)
+	d := ParseDocument(s, "SimpleClass.java")
+
+	sourceVisitor := SourceVisitor{Linker: &SourceLinker{Template: "https://example.com/{path}#L{line}"}}
+	sourceVisitor.visit(d)
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(d)
+
+	templates, err := LoadTemplates(Flavors["docusaurus"], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var page strings.Builder
+	if err = templates.ExecuteTemplate(&page, "class", makePageData(d, symbolVisitor.Symbols, Flavors["docusaurus"])); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"# SimpleClass\n\n[Source](https://example.com/SimpleClass.java#L5)\n\n",
+		"{#add(int,int)}\n\n[Source](https://example.com/SimpleClass.java#L9)\n\n",
+	} {
+		if !strings.Contains(page.String(), expected) {
+			t.Errorf("expected %q in page %q", expected, page.String())
+		}
+	}
+}
@@ -6,6 +6,11 @@
 
 package parser
 
+import (
+	"fmt"
+	"strings"
+)
+
 type SymbolType int
 
 const (
@@ -17,6 +22,36 @@ const (
 	SYM_TYPE_FIELD
 )
 
+func (t SymbolType) String() string {
+	switch t {
+	case SYM_TYPE_CLASS:
+		return "class"
+	case SYM_TYPE_INTERFACE:
+		return "interface"
+	case SYM_TYPE_ENUM:
+		return "enum"
+	case SYM_TYPE_METHOD:
+		return "method"
+	case SYM_TYPE_FIELD:
+		return "field"
+	}
+	return "invalid"
+}
+
+func (t SymbolType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *SymbolType) UnmarshalText(text []byte) error {
+	for symbolType := SYM_TYPE_INVALID; symbolType <= SYM_TYPE_FIELD; symbolType++ {
+		if symbolType.String() == string(text) {
+			*t = symbolType
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown symbol type %q", text)
+}
+
 type Symbol struct {
 	Type          SymbolType
 	Name          string // Short name
@@ -24,6 +59,53 @@ type Symbol struct {
 	Package       string
 	Parent        string // Fields, methods, inner classes
 	Location      string
+	URL           string // Set for symbols documented elsewhere, i.e. in the JDK
 }
 
-type SymbolMap map[string]Symbol
+// A SymbolMap holds every symbol known to the transpiler, under each name a
+// link may refer to it by (i.e. "com.example.Foo#bar(int)" and
+// "Foo#bar(int)"). Methods are indexed by the name they share with their
+// overloads, so that links without parameters needn't search every symbol.
+type SymbolMap struct {
+	symbols map[string]Symbol
+	methods map[string]map[string]Symbol // i.e. "Foo#bar" to "Foo#bar(int)"
+}
+
+func NewSymbolMap() SymbolMap {
+	return SymbolMap{symbols: make(map[string]Symbol), methods: make(map[string]map[string]Symbol)}
+}
+
+// Lookup returns the symbol recorded under name.
+func (m SymbolMap) Lookup(name string) (Symbol, bool) {
+	symbol, found := m.symbols[name]
+	return symbol, found
+}
+
+// Add records symbol under name, replacing any symbol already recorded
+// under it.
+func (m SymbolMap) Add(name string, symbol Symbol) {
+	m.Delete(name)
+	m.symbols[name] = symbol
+
+	if overloadName, _, isMethod := strings.Cut(name, "("); isMethod && symbol.Type == SYM_TYPE_METHOD {
+		if m.methods[overloadName] == nil {
+			m.methods[overloadName] = make(map[string]Symbol)
+		}
+		m.methods[overloadName][name] = symbol
+	}
+}
+
+// Delete removes the symbol recorded under name.
+func (m SymbolMap) Delete(name string) {
+	delete(m.symbols, name)
+
+	overloadName, _, _ := strings.Cut(name, "(")
+	if overloads := m.methods[overloadName]; overloads != nil {
+		delete(overloads, name)
+	}
+}
+
+// All returns every symbol, keyed by each name it's recorded under.
+func (m SymbolMap) All() map[string]Symbol {
+	return m.symbols
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// A Syntax produces the inline markup used when interpolating Javadoc text
+// for a particular output format.
+type Syntax interface {
+	// CodeSpan returns the delimiters around inline code
+	CodeSpan() (start string, end string)
+
+	// CodeBlock returns the lines around a block of code in the given language
+	CodeBlock(language string) (start string, end string)
+
+	// Link returns a link to target with the given label, which has already
+	// been marked up
+	Link(label string, target string) string
+
+	// LinkSpan returns the delimiters around the label of a link to target
+	LinkSpan(target string) (start string, end string)
+
+	// Emphasis returns emphasized text
+	Emphasis(text string) string
+
+	// Tag returns an HTML tag which is passed through from the Javadoc
+	Tag(tag string) string
+
+	// Literal escapes text which must appear exactly as written, such as the
+	// content of a {@code} tag
+	Literal(text string) string
+
+	// Text escapes a line of Javadoc prose, which may itself contain HTML
+	// entities
+	Text(text string) string
+}
+
+type MarkdownSyntax struct{}
+
+func (MarkdownSyntax) CodeSpan() (string, string) {
+	return "`", "`"
+}
+
+func (MarkdownSyntax) CodeBlock(language string) (string, string) {
+	return "```" + language, "```"
+}
+
+func (MarkdownSyntax) Link(label string, target string) string {
+	return "[" + label + "](" + target + ")"
+}
+
+func (MarkdownSyntax) LinkSpan(target string) (string, string) {
+	return "[", "](" + target + ")"
+}
+
+func (MarkdownSyntax) Emphasis(text string) string {
+	return "*" + text + "*"
+}
+
+func (MarkdownSyntax) Tag(tag string) string {
+	return tag
+}
+
+func (MarkdownSyntax) Literal(text string) string {
+	return text
+}
+
+func (MarkdownSyntax) Text(text string) string {
+	return text
+}
+
+type AsciiDocSyntax struct{}
+
+// Code is written as literal monospace, so that nothing inside it is
+// mistaken for markup.
+func (AsciiDocSyntax) CodeSpan() (string, string) {
+	return "`+", "+`"
+}
+
+func (AsciiDocSyntax) CodeBlock(language string) (string, string) {
+	return "[source," + language + "]\n----", "----"
+}
+
+// Pages within the site are cross-referenced, but xref: only takes their
+// resource IDs, so absolute URLs (i.e. to external Javadoc) are linked.
+func (AsciiDocSyntax) Link(label string, target string) string {
+	if u, err := url.Parse(target); err == nil && u.IsAbs() {
+		return "link:" + target + "[" + label + "]"
+	}
+	return "xref:" + target + "[" + label + "]"
+}
+
+func (AsciiDocSyntax) LinkSpan(target string) (string, string) {
+	return "link:" + target + "[", "]"
+}
+
+func (AsciiDocSyntax) Emphasis(text string) string {
+	return "_" + text + "_"
+}
+
+// HTML is passed straight through to the HTML backend.
+func (AsciiDocSyntax) Tag(tag string) string {
+	return "+++" + tag + "+++"
+}
+
+func (AsciiDocSyntax) Literal(text string) string {
+	return text
+}
+
+func (AsciiDocSyntax) Text(text string) string {
+	return text
+}
+
+// HTMLSyntax is used for the static site. Javadoc is already HTML, so tags
+// are passed through untouched.
+type HTMLSyntax struct{}
+
+func (HTMLSyntax) CodeSpan() (string, string) {
+	return "<code>", "</code>"
+}
+
+func (HTMLSyntax) CodeBlock(language string) (string, string) {
+	return "<pre><code class=\"language-" + language + "\">", "</code></pre>"
+}
+
+func (HTMLSyntax) Link(label string, target string) string {
+	return "<a href=\"" + html.EscapeString(target) + "\">" + label + "</a>"
+}
+
+func (HTMLSyntax) LinkSpan(target string) (string, string) {
+	return "<a href=\"" + html.EscapeString(target) + "\">", "</a>"
+}
+
+func (HTMLSyntax) Emphasis(text string) string {
+	return "<em>" + html.EscapeString(text) + "</em>"
+}
+
+func (HTMLSyntax) Tag(tag string) string {
+	return tag
+}
+
+func (HTMLSyntax) Literal(text string) string {
+	return html.EscapeString(text)
+}
+
+func (HTMLSyntax) Text(text string) string {
+	return text
+}
+
+// RoffSyntax is used for man pages. Only a handful of HTML tags have an
+// equivalent in roff, and the rest are dropped.
+type RoffSyntax struct{}
+
+func (RoffSyntax) CodeSpan() (string, string) {
+	return "\\fB", "\\fR"
+}
+
+func (RoffSyntax) CodeBlock(language string) (string, string) {
+	return "\n.PP\n.RS 4\n.nf", "\n.fi\n.RE\n"
+}
+
+// Man pages can't link anywhere, so links are only their labels.
+func (RoffSyntax) Link(label string, target string) string {
+	return label
+}
+
+func (RoffSyntax) LinkSpan(target string) (string, string) {
+	return "\\fI", "\\fR <" + target + ">"
+}
+
+func (r RoffSyntax) Emphasis(text string) string {
+	return "\\fI" + r.Literal(text) + "\\fR"
+}
+
+var roffTags = map[string]string{
+	"p": "\n.PP\n", "br": "\n.br\n",
+	"b": "\\fB", "strong": "\\fB", "i": "\\fI", "em": "\\fI", "code": "\\fB", "tt": "\\fB",
+}
+
+func (RoffSyntax) Tag(tag string) string {
+	t := XMLTag{Tag: tag}
+
+	// Every closing tag which has a font change returns to the regular font
+	if strings.HasPrefix(tag, "</") {
+		if replacement := roffTags[t.Type()]; strings.HasPrefix(replacement, "\\f") {
+			return "\\fR"
+		}
+		return ""
+	}
+
+	// Self-closed font changes are empty, so must not change the font
+	if replacement := roffTags[t.Type()]; !strings.HasSuffix(tag, "/>") || !strings.HasPrefix(replacement, "\\f") {
+		return replacement
+	}
+	return ""
+}
+
+var roffEscaper = strings.NewReplacer("\\", "\\e", "-", "\\-")
+
+func (RoffSyntax) Literal(text string) string {
+	return roffEscaper.Replace(text)
+}
+
+// Entities are unescaped before the text is escaped, so that an escaped
+// backslash (&#92;) is still escaped in roff.
+func (RoffSyntax) Text(text string) string {
+	text = roffEscaper.Replace(html.UnescapeString(text))
+
+	// Lines beginning with a period or apostrophe would be read as requests,
+	// and leading whitespace is trimmed from lines later
+	trimmed := strings.TrimLeft(text, " \t")
+	if strings.HasPrefix(trimmed, ".") || strings.HasPrefix(trimmed, "'") {
+		text = text[:len(text)-len(trimmed)] + "\\&" + trimmed
+	}
+
+	return text
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// LoadTemplates builds the template set used to render pages in the given
+// flavor. Each "<name>.<ext>.tmpl" file defines the template <name>; the
+// templates the flavor shares with others are loaded first, then its own
+// built-in templates, and any template found in directory (if one is given)
+// replaces the built-in template of the same name.
+//
+// Every flavor provides at least the "class", "member" and "index" templates.
+func LoadTemplates(flavor *Flavor, directory string) (*template.Template, error) {
+	t := template.New("").Funcs(templateFuncs).Funcs(template.FuncMap{"anchor": flavor.Anchor})
+
+	for _, name := range []string{flavor.SharedTemplates, flavor.Templates} {
+		if name == "" {
+			continue
+		}
+
+		builtin, err := fs.Sub(builtinTemplates, "templates/"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = parseTemplates(t, builtin); err != nil {
+			return nil, err
+		}
+	}
+
+	if directory == "" {
+		return t, nil
+	}
+
+	if err := parseTemplates(t, os.DirFS(directory)); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func parseTemplates(t *template.Template, fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "*.tmpl")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		name, _, _ := strings.Cut(file, ".")
+		if _, err = t.New(name).Parse(string(content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var templateFuncs = template.FuncMap{
+	// indent prefixes every line but the first with the given number of
+	// spaces, for content nested under a list item or admonition
+	"indent": func(spaces int, s string) string {
+		return strings.ReplaceAll(s, "\n", "\n"+strings.Repeat(" ", spaces))
+	},
+	// prefix is like indent, but prefixes each line with an arbitrary
+	// string, i.e. "> " for a blockquote
+	"prefix": func(prefix string, s string) string {
+		return strings.ReplaceAll(s, "\n", "\n"+prefix)
+	},
+	"upper": strings.ToUpper,
+	"join":  func(elems []string, sep string) string { return strings.Join(elems, sep) },
+}
+
+// PageData is handed to the "class" template, and describes a single
+// Document.
+type PageData struct {
+	Document *Document         // The document being rendered
+	Class    SectionData       // The type declared by the document (its first block)
+	Members  []SectionData     // Every other block in the document, in source order
+	Symbols  map[string]Symbol // Every symbol known to the transpiler
+	Flavor   *Flavor
+	Findings []Finding // Problems found by lint, which are only shown when previewing
+}
+
+// SectionData is handed to the "member" template, and is also used for the
+// overview of the class itself. All Javadoc text has already been
+// interpolated, so links and inline tags are resolved.
+type SectionData struct {
+	Block         *Block
+	Name          string
+	QualifiedName string
+	Definition    string
+	Text          string
+	IsDeprecated  bool
+	Deprecated    string
+	HasArguments  bool
+	Params        []ParamData // Declared arguments first, then any extra @param tags
+	HasReturn     bool
+	Return        string
+	Tags          map[string]string // Every block tag, keyed by name (i.e. "@since")
+	SourceURL     string            // A link to the declaration's source, if configured
+	Undocumented  bool              // Set for declarations without a Javadoc comment, if they're to be flagged
+}
+
+// ParamData describes a single parameter of a member.
+type ParamData struct {
+	Name        string
+	Description string
+	Documented  bool // False if the argument has no matching @param tag
+}
+
+// IndexData is handed to the "index" template.
+type IndexData struct {
+	Pages    []IndexEntry   // One entry per rendered page, sorted by name
+	Packages []PackageEntry // The same pages, grouped by package
+	Symbols  map[string]Symbol
+	Flavor   *Flavor
+}
+
+// IndexEntry describes a single page listed in the index.
+type IndexEntry struct {
+	Document *Document
+	Name     string
+	Package  string
+	Link     string
+	Summary  string // The first sentence of the class overview
+}
+
+// PackageEntry describes all pages in a single package. Pages declared
+// without a package are listed under an empty Name.
+type PackageEntry struct {
+	Name  string
+	Pages []IndexEntry
+}
+
+func makePageData(doc *Document, symbols SymbolMap, flavor *Flavor) PageData {
+	data := PageData{
+		Document: doc,
+		Symbols:  symbols.All(),
+		Flavor:   flavor,
+	}
+
+	for i := range doc.Blocks {
+		section := makeSectionData(doc, &doc.Blocks[i], symbols, flavor)
+		if i == 0 {
+			data.Class = section
+		} else {
+			data.Members = append(data.Members, section)
+		}
+	}
+
+	return data
+}
+
+// flagUndocumented marks every section whose declaration has no Javadoc
+// comment, so that templates can call it out.
+func (p *PageData) flagUndocumented() {
+	p.Class.Undocumented = !p.Class.Block.HasComment()
+	for i := range p.Members {
+		p.Members[i].Undocumented = !p.Members[i].Block.HasComment()
+	}
+}
+
+func makeSectionData(doc *Document, block *Block, symbols SymbolMap, flavor *Flavor) SectionData {
+	section := SectionData{
+		Block:         block,
+		Name:          block.Name,
+		QualifiedName: block.QualifiedName,
+		Definition:    block.Definition,
+		Text:          block.Text.Interpolate(doc, symbols, flavor, ""),
+		HasArguments:  len(block.Arguments) > 0,
+		Tags:          make(map[string]string),
+		SourceURL:     block.SourceURL,
+	}
+
+	for k, v := range block.Tags {
+		section.Tags[k] = v.Interpolate(doc, symbols, flavor, "")
+	}
+
+	section.Deprecated, section.IsDeprecated = section.Tags["@deprecated"]
+	section.Return, section.HasReturn = section.Tags["@return"]
+
+	// First iterate over any arguments, detecting undocumented fields in the process
+	resolved := map[string]bool{}
+	for _, arg := range block.Arguments {
+		param := ParamData{Name: arg.Name}
+		if description, found := block.Params[arg.Name]; found {
+			param.Description = description.Interpolate(doc, symbols, flavor, "")
+			param.Documented = true
+		}
+		resolved[arg.Name] = true
+		section.Params = append(section.Params, param)
+	}
+
+	// Now add anything "extra" in our params, sorted so that pages don't
+	// change between runs
+	for _, k := range sortedKeys(block.Params) {
+		if _, found := resolved[k]; !found {
+			v := block.Params[k]
+			section.Params = append(section.Params, ParamData{
+				Name:        k,
+				Description: v.Interpolate(doc, symbols, flavor, ""),
+				Documented:  true,
+			})
+		}
+	}
+
+	return section
+}
+
+func makeIndexData(docs []*Document, symbols SymbolMap, flavor *Flavor, skipPrivate bool) IndexData {
+	data := IndexData{Symbols: symbols.All(), Flavor: flavor}
+
+	for _, doc := range docs {
+		if !doc.HasClass() {
+			continue
+		}
+
+		class := &doc.Blocks[0]
+		if skipPrivate && class.Attributes["visibility"] == "private" {
+			continue
+		}
+
+		data.Pages = append(data.Pages, IndexEntry{
+			Document: doc,
+			Name:     class.Name,
+			Package:  doc.Package,
+			Link:     flavor.Link(class.Name),
+			Summary:  firstSentence(class.Text.Interpolate(doc, symbols, flavor, "")),
+		})
+	}
+
+	sort.SliceStable(data.Pages, func(i, j int) bool {
+		return data.Pages[i].Name < data.Pages[j].Name
+	})
+
+	packages := map[string]int{}
+	for _, page := range data.Pages {
+		i, found := packages[page.Package]
+		if !found {
+			i = len(data.Packages)
+			packages[page.Package] = i
+			data.Packages = append(data.Packages, PackageEntry{Name: page.Package})
+		}
+		data.Packages[i].Pages = append(data.Packages[i].Pages, page)
+	}
+
+	sort.SliceStable(data.Packages, func(i, j int) bool {
+		return data.Packages[i].Name < data.Packages[j].Name
+	})
+
+	return data
+}
+
+// firstSentence returns the summary sentence of some text, which (like
+// javadoc) ends at the first period followed by whitespace.
+func firstSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if i := strings.Index(text, ". "); i != -1 {
+		return text[:i+1]
+	}
+
+	return text
+}
@@ -0,0 +1,391 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func renderClass(t *testing.T, input string, flavor *Flavor, templateDirectory string) string {
+	templates, err := LoadTemplates(flavor, templateDirectory)
+	if err != nil {
+		t.Fatalf("could not load templates: %s", err)
+	}
+
+	s := BeginScanningJavaCode("Test", input)
+	d := ParseDocument(s, "Test.java")
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(d)
+
+	var page bytes.Buffer
+	if err = templates.ExecuteTemplate(&page, "class", makePageData(d, symbolVisitor.Symbols, flavor)); err != nil {
+		t.Fatalf("could not render page: %s", err)
+	}
+
+	return page.String()
+}
+
+func TestDefaultTemplate(t *testing.T) {
+	input := `
+/**
+ * A Simple Class
+ */
+public class SimpleClass {
+	/**
+	 * Adds two numbers together
+	 *
+	 * @param a the first number
+	 * @return the sum
+	 */
+	public int add(int a, int b);
+}`
+	expected := "# SimpleClass\n\n## Definition\n\n```java\npublic class SimpleClass\n```\n\n" +
+		"## Overview\n\nA Simple Class\n\n" +
+		"### `public int add(int a, int b)` {#add(int,int)}\n\nAdds two numbers together\n\n" +
+		"**Parameters:**\n\n* `a` - the first number\n* `b` - *Undocumented*\n\n" +
+		"**Returns:** the sum\n\n\n"
+
+	if page := renderClass(t, input, Flavors["docusaurus"], ""); page != expected {
+		t.Errorf("got %q, wanted %q", page, expected)
+	}
+}
+
+func TestOverriddenMemberTemplate(t *testing.T) {
+	directory := t.TempDir()
+	member := "- {{ .Name }}{{ range .Params }} {{ .Name }}{{ end }}\n"
+	if err := os.WriteFile(filepath.Join(directory, "member.md.tmpl"), []byte(member), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `
+/**
+ * A Simple Class
+ */
+public class SimpleClass {
+	/**
+	 * Adds two numbers together
+	 */
+	public int add(int a, int b);
+}`
+	expected := "# SimpleClass\n\n## Definition\n\n```java\npublic class SimpleClass\n```\n\n" +
+		"## Overview\n\nA Simple Class\n\n- add a b\n"
+
+	if page := renderClass(t, input, Flavors["docusaurus"], directory); page != expected {
+		t.Errorf("got %q, wanted %q", page, expected)
+	}
+}
+
+func TestMkDocsFlavor(t *testing.T) {
+	input := `
+/**
+ * A Simple Class
+ *
+ * @deprecated Use {@link #add(int,int)} instead
+ *     of this class.
+ */
+public class SimpleClass {
+	/**
+	 * Adds two numbers together
+	 */
+	public int add(int a, int b);
+}`
+	expected := "# SimpleClass\n\n## Definition\n\n```java\npublic class SimpleClass\n```\n\n" +
+		"## Overview\n\n!!! warning \"Deprecated\"\n\n" +
+		"    Use [`add(int, int)`](SimpleClass.md#add(int,int)) instead\n        of this class.\n\n" +
+		"A Simple Class\n\n" +
+		"### `public int add(int a, int b)` { #add(int,int) }\n\nAdds two numbers together\n\n" +
+		"**Parameters:**\n\n* `a` - *Undocumented*\n* `b` - *Undocumented*\n\n"
+
+	if page := renderClass(t, input, Flavors["mkdocs"], ""); page != expected {
+		t.Errorf("got %q, wanted %q", page, expected)
+	}
+}
+
+func TestGFMFlavor(t *testing.T) {
+	input := `
+/**
+ * A Simple Class
+ *
+ * @deprecated Use {@link #add(int,int)} instead
+ * of this class.
+ */
+public class SimpleClass {
+	/**
+	 * Adds two numbers together
+	 *
+	 * @return the sum
+	 */
+	public int add(int a, int b);
+}`
+	expected := "# SimpleClass\n\n## Definition\n\n```java\npublic class SimpleClass\n```\n\n" +
+		"## Overview\n\n> **Deprecated:** Use [`add(int, int)`](SimpleClass.md#add(int,int)) instead\n> of this class.\n\n" +
+		"A Simple Class\n\n" +
+		"<a id=\"add(int,int)\"></a>\n### `public int add(int a, int b)`\n\nAdds two numbers together\n\n" +
+		"**Parameters:**\n\n* `a` - *Undocumented*\n* `b` - *Undocumented*\n\n" +
+		"**Returns:** the sum\n\n\n"
+
+	if page := renderClass(t, input, Flavors["gfm"], ""); page != expected {
+		t.Errorf("got %q, wanted %q", page, expected)
+	}
+}
+
+func TestAsciiDocFlavor(t *testing.T) {
+	input := `
+package com.example;
+
+/**
+ * A Simple Class, see <a href="https://example.com">here</a>.
+ *
+ * @deprecated Use {@link #add(int,int)} instead
+ */
+public class SimpleClass {
+	/**
+	 * Adds two {@code int}s together
+	 *
+	 * <pre>
+	 * add(1, 2);
+	 * </pre>
+	 */
+	public int add(int a, int b);
+}`
+	expected := "= SimpleClass\n\n[source,java]\n----\nimport com.example.SimpleClass;\n----\n\n" +
+		"== Definition\n\n[source,java]\n----\npublic class SimpleClass\n----\n\n" +
+		"== Overview\n\n[WARNING]\n.Deprecated\n====\nUse xref:SimpleClass.adoc#add-int-int-[`+add(int, int)+`] instead\n====\n\n" +
+		"A Simple Class, see link:https://example.com[here].\n\n" +
+		"[#add-int-int-]\n=== `+public int add(int a, int b)+`\n\nAdds two `+int+`s together\n\n" +
+		"[source,java]\n----\nadd(1, 2);\n----\n\n" +
+		"*Parameters:*\n\n* `+a+` - _Undocumented_\n* `+b+` - _Undocumented_\n\n"
+
+	if page := renderClass(t, input, Flavors["asciidoc"], ""); page != expected {
+		t.Errorf("got %q, wanted %q", page, expected)
+	}
+}
+
+func TestFlagUndocumented(t *testing.T) {
+	input := `
+public class SimpleClass {
+	/**
+	 * Adds two numbers together
+	 */
+	public int add(int a, int b);
+
+	public int subtract(int a, int b);
+}`
+	templates, err := LoadTemplates(Flavors["docusaurus"], "")
+	if err != nil {
+		t.Fatalf("could not load templates: %s", err)
+	}
+
+	d := ParseDocument(BeginScanningJavaCode("Test", input), "Test.java")
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(d)
+
+	data := makePageData(d, symbolVisitor.Symbols, Flavors["docusaurus"])
+	data.flagUndocumented()
+
+	var page bytes.Buffer
+	if err = templates.ExecuteTemplate(&page, "class", data); err != nil {
+		t.Fatalf("could not render page: %s", err)
+	}
+
+	expected := "### `public int add(int a, int b)` {#add(int,int)}\n\nAdds two numbers together\n\n"
+	if !strings.Contains(page.String(), expected) {
+		t.Errorf("documented member was flagged in %q", page.String())
+	}
+
+	expected = "### `public int subtract(int a, int b)` {#subtract(int,int)}\n\n*Undocumented*\n\n"
+	if !strings.Contains(page.String(), expected) {
+		t.Errorf("undocumented member wasn't flagged in %q", page.String())
+	}
+}
+
+func TestEmptyInlineTags(t *testing.T) {
+	input := `
+/**
+ * Ends {@code} and more, then {@link}
+ * and ends with {@code}
+ */
+public class Empty {}`
+
+	d := ParseDocument(BeginScanningJavaCode("Test", input), "Empty.java")
+
+	// Every flavor renders empty tags without taking the text after them
+	for name, flavor := range Flavors {
+		if flavor.Syntax == nil {
+			continue
+		}
+
+		text := d.Blocks[0].Text.Interpolate(d, SymbolMap{}, flavor, "")
+		if !strings.Contains(text, " and more, then ") || !strings.Contains(text, "and ends with ") {
+			t.Errorf("%s: got %q, wanted the text around the empty tags", name, text)
+		}
+	}
+
+	text := d.Blocks[0].Text.Interpolate(d, SymbolMap{}, Flavors["docusaurus"], "")
+	expected := "Ends `` and more, then **\nand ends with ``"
+	if text != expected {
+		t.Errorf("got %q, wanted %q", text, expected)
+	}
+}
+
+func TestSkipPrivateMembers(t *testing.T) {
+	input := `
+/**
+ * A class with private members
+ */
+public class Guarded {
+	/**
+	 * Shown to everyone
+	 */
+	public void shown();
+
+	/**
+	 * Hidden from everyone
+	 */
+	private void documented();
+
+	private int secret;
+
+	private void hidden();
+
+	int packaged;
+}`
+	templates, err := LoadTemplates(Flavors["docusaurus"], "")
+	if err != nil {
+		t.Fatalf("could not load templates: %s", err)
+	}
+
+	d := ParseDocument(BeginScanningJavaCode("Test", input), "Guarded.java")
+
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	symbolVisitor.visit(d)
+
+	directory := t.TempDir()
+	visitor := MarkdownVisitor{
+		OutputDirectory: directory,
+		SkipPrivateDefs: true,
+		Symbols:         symbolVisitor.Symbols,
+		Templates:       templates,
+		Flavor:          Flavors["docusaurus"],
+	}
+	if err := visitor.visit(d); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(directory, "Guarded.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"shown", "packaged"} {
+		if !strings.Contains(string(page), name) {
+			t.Errorf("%s is missing from %q", name, page)
+		}
+	}
+
+	for _, name := range []string{"documented", "secret", "hidden"} {
+		if strings.Contains(string(page), name) {
+			t.Errorf("private member %s was rendered in %q", name, page)
+		}
+	}
+
+	if len(d.Blocks) != 6 {
+		t.Errorf("got %d blocks, wanted the document to keep all 6", len(d.Blocks))
+	}
+}
+
+func TestExtraParamOrder(t *testing.T) {
+	input := `
+public class Test {
+	/**
+	 * Does something
+	 *
+	 * @param a the only real parameter
+	 * @param zeta an extra parameter
+	 * @param gamma an extra parameter
+	 * @param beta an extra parameter
+	 * @param delta an extra parameter
+	 * @param alpha an extra parameter
+	 */
+	public void something(int a);
+}`
+
+	first := renderClass(t, input, Flavors["docusaurus"], "")
+	for i := 0; i < 10; i++ {
+		if page := renderClass(t, input, Flavors["docusaurus"], ""); page != first {
+			t.Fatalf("got %q, then %q", first, page)
+		}
+	}
+
+	d := ParseDocument(BeginScanningJavaCode("Test", input), "Test.java")
+	section := makeSectionData(d, &d.Blocks[1], NewSymbolMap(), Flavors["docusaurus"])
+
+	var names []string
+	for _, param := range section.Params {
+		names = append(names, param.Name)
+	}
+
+	expected := "a alpha beta delta gamma zeta"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("got params %v, wanted %s", names, expected)
+	}
+}
+
+func TestRemovePages(t *testing.T) {
+	input := `
+/**
+ * A command
+ */
+@Command(name = "go-away")
+public class Leaving {}`
+
+	for _, flavor := range []string{"docusaurus", "html", "json", "man"} {
+		directory := t.TempDir()
+		options := VisitorConfigOptions{
+			OutputDirectory: directory,
+			Flavor:          Flavors[flavor],
+			ManAnnotation:   "Command",
+		}
+
+		docs := make(chan *Document, 1)
+		docs <- ParseDocument(BeginScanningJavaCode("Leaving", input), "Leaving.java")
+		close(docs)
+
+		if err := VisitDocuments(&options, docs); err != nil {
+			t.Fatal(err)
+		}
+
+		page := "Leaving" + Flavors[flavor].Extension
+		if flavor == "man" {
+			page = "go-away.1"
+		}
+
+		if _, err := os.Stat(filepath.Join(directory, page)); err != nil {
+			t.Fatalf("%s: %s wasn't written: %v", flavor, page, err)
+		}
+
+		d := ParseDocument(BeginScanningJavaCode("Leaving", input), "Leaving.java")
+		if err := RemovePages(&options, d); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(filepath.Join(directory, page)); err == nil {
+			t.Errorf("%s: %s wasn't removed", flavor, page)
+		}
+
+		// Removing pages which are already gone is fine
+		if err := RemovePages(&options, d); err != nil {
+			t.Errorf("%s: %v", flavor, err)
+		}
+	}
+}
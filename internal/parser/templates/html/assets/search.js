@@ -0,0 +1,59 @@
+(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("search-results");
+  var index = window.searchIndex || [];
+
+  function render(query) {
+    results.innerHTML = "";
+    query = query.trim().toLowerCase();
+    if (query === "") {
+      return;
+    }
+
+    var matches = index.filter(function (entry) {
+      return entry.name.toLowerCase().indexOf(query) !== -1;
+    });
+
+    // Prefer exact matches, then prefix matches, then everything else
+    matches.sort(function (a, b) {
+      return rank(a, query) - rank(b, query) || a.name.localeCompare(b.name);
+    });
+
+    matches.slice(0, 20).forEach(function (entry) {
+      var link = document.createElement("a");
+      link.href = entry.url;
+      link.textContent = entry.parent ? entry.parent + "." + entry.name : entry.name;
+
+      var kind = document.createElement("span");
+      kind.className = "kind";
+      kind.textContent = entry.kind;
+      link.appendChild(kind);
+
+      if (entry.summary) {
+        link.title = entry.summary;
+      }
+
+      var item = document.createElement("li");
+      item.appendChild(link);
+      results.appendChild(item);
+    });
+  }
+
+  function rank(entry, query) {
+    var name = entry.name.toLowerCase();
+    if (name === query) {
+      return 0;
+    }
+    return name.indexOf(query) === 0 ? 1 : 2;
+  }
+
+  input.addEventListener("input", function () {
+    render(input.value);
+  });
+
+  input.addEventListener("keydown", function (event) {
+    if (event.key === "Enter" && results.firstChild) {
+      window.location.href = results.firstChild.firstChild.href;
+    }
+  });
+})();
@@ -8,6 +8,7 @@ package parser
 
 import (
 	"strings"
+	"unicode"
 )
 
 type stack []XMLTag
@@ -32,6 +33,18 @@ func (s *stack) Pop() (j XMLTag, empty bool) {
 	return
 }
 
+// Contains returns whether any tag of the given types is on the stack.
+func (s *stack) Contains(tagTypes ...string) bool {
+	for _, tag := range *s {
+		for _, tagType := range tagTypes {
+			if tag.Type() == tagType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (s *stack) Peek() (j XMLTag, empty bool) {
 	if s.Empty() {
 		j = XMLTag{}
@@ -43,6 +56,112 @@ func (s *stack) Peek() (j XMLTag, empty bool) {
 	return
 }
 
+func isLink(tag string) bool {
+	return tag == "@link" || tag == "@linkplain"
+}
+
+// splitLink splits the content of a link tag into its reference and its
+// label, which follows the reference after whitespace, i.e. "Foo#bar(int, int)
+// the bar method".
+func splitLink(content string) (reference string, label string) {
+	content = strings.TrimSpace(content)
+
+	depth := 0
+	for i, c := range content {
+		switch {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case unicode.IsSpace(c) && depth == 0:
+			return content[:i], strings.Join(strings.Fields(content[i:]), " ")
+		}
+	}
+
+	return content, ""
+}
+
+// normalizeReference removes whitespace from a reference, as well as the
+// names and type arguments of any parameters, since neither are part of a
+// method's qualified name.
+func normalizeReference(reference string) string {
+	name, params, isMethod := strings.Cut(reference, "(")
+	normalized := strings.Join(strings.Fields(name), "")
+
+	if isMethod {
+		params, _, _ = strings.Cut(params, ")")
+
+		var types []string
+		for _, param := range splitParameters(params) {
+			if fields := strings.Fields(erasure(param)); len(fields) > 0 {
+				types = append(types, fields[0])
+			}
+		}
+
+		normalized += "(" + strings.Join(types, ",") + ")"
+	}
+
+	return normalized
+}
+
+// linkTarget returns the symbol referred to by the content of a link tag.
+func linkTarget(doc *Document, content string) string {
+	reference, _ := splitLink(content)
+	target := normalizeReference(reference)
+
+	// Handle links local to the current class
+	if strings.HasPrefix(target, "#") {
+		target = doc.Blocks[0].Name + target
+	}
+
+	return target
+}
+
+// linkLabel returns the label javadoc gives a link which doesn't have one:
+// the simple name of the class, followed by the member, if any. Methods are
+// written with their parameter types, i.e. "Foo.bar(List, int)".
+func linkLabel(reference string, symbol Symbol) string {
+	class, member, isMember := strings.Cut(normalizeReference(reference), "#")
+
+	if symbol.Type == SYM_TYPE_METHOD {
+		member = symbol.QualifiedName
+	}
+
+	if name, params, isMethod := strings.Cut(member, "("); isMethod {
+		var types []string
+		for _, param := range splitParameters(strings.TrimSuffix(params, ")")) {
+			if param != "" {
+				types = append(types, simpleType(param))
+			}
+		}
+
+		member = name + "(" + strings.Join(types, ", ") + ")"
+	}
+
+	// Drop the package, which by convention is lowercase
+	segments := strings.Split(class, ".")
+	for len(segments) > 1 && !unicode.IsUpper([]rune(segments[0] + " ")[0]) {
+		segments = segments[1:]
+	}
+	class = strings.Join(segments, ".")
+
+	switch {
+	case !isMember:
+		return class
+	case class == "":
+		return member
+	}
+
+	return class + "." + member
+}
+
+// simpleType drops the package from a (possibly qualified) type name, i.e.
+// "java.lang.String[]" becomes "String[]".
+func simpleType(t string) string {
+	name := strings.TrimRight(t, "[].")
+	return name[strings.LastIndex(name, ".")+1:] + t[len(name):]
+}
+
 // Really, we should be building an AST since Javadoc can have parameters
 // virtually anywhere, but storing token lists in Blocks is simpler for now.
 type Text []Token
@@ -51,11 +170,61 @@ func (t *Text) Length() int {
 	return len(*t)
 }
 
+// Lines splits the text at each newline, dropping empty lines. This is useful
+// for tags like @see, which may appear several times in the same block.
+func (t *Text) Lines() []Text {
+	var lines []Text
+	var line Text
+
+	for _, token := range *t {
+		if token.Type == TOK_JDOC_NL {
+			if len(line) > 0 {
+				lines = append(lines, line)
+			}
+			line = nil
+			continue
+		}
+
+		// Skip the whitespace between lines
+		if len(line) == 0 && token.Type == TOK_JDOC_LINE && strings.TrimSpace(token.Lexeme) == "" {
+			continue
+		}
+
+		line = append(line, token)
+	}
+
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// paramContents returns the contents of the inline tag at i, and whether
+// there's a token holding them. A tag at the end of a comment which is never
+// closed has none.
+func (t *Text) paramContents(i int) (string, bool) {
+	if i+1 >= t.Length() {
+		return "", false
+	}
+
+	switch next := (*t)[i+1]; next.Type {
+	case TOK_JDOC_LINE, TOK_JSX_O, TOK_JSX_X:
+		return next.Lexeme, true
+	}
+
+	return "", false
+}
+
 // Given a Text token list, return a string with all the parameters
 // evaluated.
-func (t *Text) Interpolate(doc *Document, symbols SymbolMap, flowIndent string) string {
+func (t *Text) Interpolate(doc *Document, symbols SymbolMap, flavor *Flavor, flowIndent string) string {
 	interpolationArray := make([]string, t.Length())
 	jsxStack := stack{}
+	syntax := flavor.Syntax
+	// Number of bytes to drop from the start of the next line, when they
+	// were already emitted along with the previous token
+	trim := 0
 
 	for i := 0; i < t.Length(); i++ {
 		token := (*t)[i]
@@ -63,57 +232,113 @@ func (t *Text) Interpolate(doc *Document, symbols SymbolMap, flowIndent string)
 		switch token.Type {
 		case TOK_JDOC_NL:
 			interpolationArray[i] = "\n" + flowIndent
+		case TOK_JDOC_LINE:
+			lexeme := syntax.Text(token.Lexeme[trim:])
+			trim = 0
+
+			if flavor.MDX && !jsxStack.Contains("pre", "code") {
+				lexeme = escapeMDX(lexeme)
+			}
+
+			interpolationArray[i] = lexeme
 		case TOK_JDOC_PARAM:
 			str := ""
 			if token.Lexeme == "@code" {
-				inPre := false
 				// Check if for weird interactions, like being inside a <pre> tag, which
 				// cancels us out.
-				for _, tag := range jsxStack {
-					if tag.Type() == "pre" {
-						inPre = true
-						break
-					}
-				}
+				inPre := jsxStack.Contains("pre")
 
-				if !inPre {
-					str += "`"
-				}
-
-				str += strings.TrimSpace((*t)[i+1].Lexeme)
+				contents, found := t.paramContents(i)
+				str = syntax.Literal(strings.TrimSpace(contents))
 
 				if !inPre {
-					str += "`"
+					start, end := syntax.CodeSpan()
+					str = start + str + end
+				}
+				if found {
+					i++
 				}
-				i++
 			}
 
-			if token.Lexeme == "@link" || token.Lexeme == "@linkplain" {
-				target := strings.ReplaceAll((*t)[i+1].Lexeme, " ", "")
-				target = strings.ReplaceAll(target, "\n", "")
+			if isLink(token.Lexeme) {
+				contents, found := t.paramContents(i)
+				reference, label := splitLink(contents)
+				symbol, _ := symbols.Resolve(doc, linkTarget(doc, reference))
 
-				// Handle links local to the current class
-				if target[0] == '#' {
-					target = doc.Blocks[0].Name + target
+				if label == "" {
+					label = linkLabel(reference, symbol)
+				}
+
+				var markup string
+				if token.Lexeme == "@link" {
+					start, end := syntax.CodeSpan()
+					markup = start + syntax.Literal(label) + end
+				} else if flavor.MDX {
+					markup = escapeMDX(syntax.Literal(label))
+				} else {
+					markup = syntax.Literal(label)
 				}
 
-				symbol := symbols[target]
 				if symbol.Type == SYM_TYPE_INVALID {
-					str = "*" + target + "*"
+					if flavor.MDX {
+						label = escapeMDX(label)
+					}
+					str = syntax.Emphasis(label)
+				} else if symbol.URL != "" {
+					str = syntax.Link(markup, symbol.URL)
 				} else {
-					// TODO: The name of the link should be a proper definition
-					str += "[" + symbol.Name + "](" + symbol.Location + ")"
+					str = syntax.Link(markup, flavor.Link(symbol.Location))
+				}
+				if found {
+					i++
 				}
-				i++
 			}
 
 			interpolationArray[i] = str
 		case TOK_JSX_O:
-			jsxStack.Push(XMLTag{i, token.Lexeme})
-			interpolationArray[i] = token.Lexeme
+			tag := XMLTag{i, token.Lexeme}
+
+			if flavor.MDX {
+				// HTML comments are not allowed in MDX at all
+				if strings.HasPrefix(tag.Tag, "<!--") {
+					continue
+				}
+
+				if !htmlElements[strings.ToLower(tag.Type())] {
+					interpolationArray[i], trim = t.typeArgumentsAsCode(i, interpolationArray, jsxStack)
+					continue
+				}
+
+				tag.Tag = jsxAttributes(strings.Replace(tag.Tag, tag.Type(), strings.ToLower(tag.Type()), 1))
+
+				if voidElements[tag.Type()] {
+					interpolationArray[i] = syntax.Tag(tag.Close())
+					continue
+				}
+			}
+
+			jsxStack.Push(tag)
+			interpolationArray[i] = syntax.Tag(tag.Tag)
 		case TOK_JSX_X:
 			current := XMLTag{i, token.Lexeme}
 
+			if flavor.MDX {
+				if !htmlElements[strings.ToLower(current.Type())] {
+					interpolationArray[i], trim = t.typeArgumentsAsCode(i, interpolationArray, jsxStack)
+					continue
+				}
+
+				current.Tag = strings.Replace(current.Tag, current.Type(), strings.ToLower(current.Type()), 1)
+				token.Lexeme = current.Tag
+
+				// A closing tag without an opening tag can't be balanced, so drop it
+				if !jsxStack.Contains(current.Type()) {
+					continue
+				}
+			}
+
+			interpolationArray[i] = syntax.Tag(token.Lexeme)
+
 			for {
 				next, empty := jsxStack.Pop()
 
@@ -125,26 +350,21 @@ func (t *Text) Interpolate(doc *Document, symbols SymbolMap, flowIndent string)
 					if next.Type() == "pre" || next.Type() == "code" {
 						// If there are multiple lines between our tags, consider it a long form code block
 						if i-next.Index > 2 || strings.IndexRune(interpolationArray[i-1], '\n') != -1 {
-							interpolationArray[next.Index] = "```java"
-							token.Lexeme = "```"
+							interpolationArray[next.Index], interpolationArray[i] = syntax.CodeBlock("java")
 						} else { // Otherwise it's inline plaintext
-							interpolationArray[next.Index] = "`"
-							token.Lexeme = "`"
+							interpolationArray[next.Index], interpolationArray[i] = syntax.CodeSpan()
 						}
 					}
 
 					if next.Type() == "a" {
-						interpolationArray[next.Index] = "["
-						token.Lexeme = "](" + next.Attributes()["href"] + ")"
+						interpolationArray[next.Index], interpolationArray[i] = syntax.LinkSpan(next.Attributes()["href"])
 					}
 					break
 				}
 
 				// Close the Tag
-				interpolationArray[next.Index] = next.Close()
+				interpolationArray[next.Index] = syntax.Tag(next.Close())
 			}
-
-			interpolationArray[i] = token.Lexeme
 		default:
 			interpolationArray[i] = token.Lexeme
 		}
@@ -158,8 +378,41 @@ func (t *Text) Interpolate(doc *Document, symbols SymbolMap, flowIndent string)
 			break
 		}
 
-		interpolationArray[next.Index] = next.Close()
+		interpolationArray[next.Index] = syntax.Tag(next.Close())
 	}
 
 	return strings.TrimSpace(strings.Join(interpolationArray, ""))
 }
+
+// typeArgumentsAsCode renders the "tag" at index i, which is really the type
+// arguments of a generic type such as List<String>, as a code span. The name
+// of the type is pulled out of the preceding text, and any trailing '>'
+// characters from nested type arguments are pulled in from the following
+// text; the number of those is returned so they can be trimmed.
+func (t *Text) typeArgumentsAsCode(i int, interpolationArray []string, jsxStack stack) (string, int) {
+	token := (*t)[i]
+
+	// Inside a code block the tag is just more code
+	if jsxStack.Contains("pre", "code") {
+		return token.Lexeme, 0
+	}
+
+	code := token.Lexeme
+
+	if i > 0 && (*t)[i-1].Type == TOK_JDOC_LINE {
+		head, word := splitTrailingWord(interpolationArray[i-1])
+		interpolationArray[i-1] = head
+		code = word + code
+	}
+
+	trim := 0
+	if i+1 < t.Length() && (*t)[i+1].Type == TOK_JDOC_LINE {
+		next := (*t)[i+1].Lexeme
+		for trim < len(next) && next[trim] == '>' {
+			trim++
+		}
+		code += next[:trim]
+	}
+
+	return "`" + code + "`", trim
+}
@@ -6,6 +6,8 @@
 
 package parser
 
+import "fmt"
+
 const EOF rune = 0
 
 type TokenType int
@@ -28,6 +30,7 @@ const (
 	TOK_JSX_X // </..>
 
 	// This content is java-related
+	TOK_JAVA_DECL_END // ; or {, ending a declaration
 	TOK_JAVA_KEYWORD
 	TOK_JAVA_PAREN_O
 	TOK_JAVA_PAREN_X
@@ -45,7 +48,71 @@ const (
 	TOK_JAVA_OTHER
 )
 
+var tokenTypeNames = map[TokenType]string{
+	TOK_EOF:             "EOF",
+	TOK_JDOC_START:      "JDOC_START",
+	TOK_JDOC_END:        "JDOC_END",
+	TOK_JDOC_TAG:        "JDOC_TAG",
+	TOK_JDOC_PARAM:      "JDOC_PARAM",
+	TOK_JDOC_PARAM_END:  "JDOC_PARAM_END",
+	TOK_JDOC_NL:         "JDOC_NL",
+	TOK_JDOC_LINE:       "JDOC_LINE",
+	TOK_JSX_O:           "JSX_O",
+	TOK_JSX_X:           "JSX_X",
+	TOK_JAVA_DECL_END:   "JAVA_DECL_END",
+	TOK_JAVA_KEYWORD:    "JAVA_KEYWORD",
+	TOK_JAVA_PAREN_O:    "JAVA_PAREN_O",
+	TOK_JAVA_PAREN_X:    "JAVA_PAREN_X",
+	TOK_JAVA_COMMA:      "JAVA_COMMA",
+	TOK_JAVA_EQUAL:      "JAVA_EQUAL",
+	TOK_JAVA_STRING:     "JAVA_STRING",
+	TOK_JAVA_OPERATOR:   "JAVA_OPERATOR",
+	TOK_JAVA_BRACKET_O:  "JAVA_BRACKET_O",
+	TOK_JAVA_BRACKET_X:  "JAVA_BRACKET_X",
+	TOK_JAVA_IDENTIFIER: "JAVA_IDENTIFIER",
+	TOK_JAVA_NUMERIC:    "JAVA_NUMERIC",
+	TOK_JAVA_ANNOTATION: "JAVA_ANNOTATION",
+	TOK_JAVA_COMMENT_O:  "JAVA_COMMENT_O",
+	TOK_JAVA_COMMENT_X:  "JAVA_COMMENT_X",
+	TOK_JAVA_OTHER:      "JAVA_OTHER",
+}
+
+func (t TokenType) String() string {
+	if name, ok := tokenTypeNames[t]; ok {
+		return name
+	}
+	return "INVALID"
+}
+
+func (t TokenType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *TokenType) UnmarshalText(text []byte) error {
+	for tokenType, name := range tokenTypeNames {
+		if name == string(text) {
+			*t = tokenType
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown token type %q", text)
+}
+
+// A Position is a location in a source file.
+type Position struct {
+	Offset int `json:"offset"` // In bytes, counting from 0
+	Line   int `json:"line"`   // Counting from 1
+	Column int `json:"column"` // In characters, counting from 1
+}
+
+// A Range is the span of source from Start up to, but not including, End.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
 type Token struct {
-	Type   TokenType
-	Lexeme string
+	Type   TokenType `json:"type"`
+	Lexeme string    `json:"lexeme"`
+	Range
 }
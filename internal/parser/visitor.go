@@ -7,68 +7,264 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"text/template"
+
+	"github.com/dburkart/javadoc2md/internal/logger"
 )
 
 type VisitorConfigOptions struct {
-	OutputDirectory string
-	SkipPrivateDefs bool
+	OutputDirectory   string
+	SkipPrivateDefs   bool
+	TemplateDirectory string
+	WriteIndex        bool
+	Flavor            *Flavor
+
+	// Links which can't be resolved within the input are resolved against
+	// these libraries, if possible
+	ExternalLibraries []*ExternalLibrary
+
+	// If set, a link to the source of each class and member is rendered next
+	// to its heading
+	SourceLinker *SourceLinker
+
+	// Declarations without a Javadoc comment are listed like any other, but
+	// are called out as undocumented if this is set
+	FlagUndocumented bool
+
+	// Man pages are only written for the classes listed in ManClasses, or
+	// annotated with ManAnnotation
+	ManSection    string
+	ManClasses    []string
+	ManAnnotation string
+
+	// By default, every document which can be is written, and the errors
+	// with the rest are returned together. If FailFast is set, the first
+	// error is returned straight away.
+	FailFast bool
 }
 
-func VisitDocuments(options *VisitorConfigOptions, docs chan *Document) {
-	var documents []*Document
+// VisitDocuments writes the documentation for every document. Errors with
+// single documents are returned as FileErrors, in an ErrorList unless
+// options.FailFast is set.
+func VisitDocuments(options *VisitorConfigOptions, docs chan *Document) error {
+	documents, symbols := collectSymbols(options, docs)
 
-	// The symbol visitor is special in that we want to visit _every_ document
-	// with this visitor before proceeding
-	symbolVisitor := SymbolVisitor{Symbols: make(map[string]Symbol)}
-	for {
-		doc, ok := <-docs
-		if !ok {
-			break
-		}
+	linkVisitor := LinkVisitor{Symbols: symbols}
+	for _, doc := range documents {
+		linkVisitor.visit(doc)
+	}
 
-		symbolVisitor.visit(doc)
-		documents = append(documents, doc)
+	if options.SourceLinker != nil {
+		sourceVisitor := SourceVisitor{Linker: options.SourceLinker}
+		for _, doc := range documents {
+			sourceVisitor.visit(doc)
+		}
 	}
 
-	visitors := []Visitor{
-		&MarkdownVisitor{
-			OutputDirectory: options.OutputDirectory,
-			SkipPrivateDefs: options.SkipPrivateDefs,
-			Symbols:         symbolVisitor.Symbols,
-		},
+	outputVisitor, err := makeOutputVisitor(options, symbols)
+	if err != nil {
+		return err
 	}
 
+	visitors := []OutputVisitor{outputVisitor}
+	var errs ErrorList
+
 	for _, v := range visitors {
 		for _, d := range documents {
-			if len(d.Blocks) == 0 {
+			if !d.HasClass() {
 				continue
 			}
 
-			v.visit(d)
+			if err := v.visit(d); err != nil {
+				docErr := &FileError{Op: "document", Path: d.Address, Err: err}
+				if options.FailFast {
+					return docErr
+				}
+				errs = append(errs, docErr)
+			}
+		}
+
+		if err = v.finish(documents); err != nil {
+			if options.FailFast {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.Err()
+}
+
+// RemovePages removes the pages written for a document, i.e. once its source
+// has been deleted. Files which cover every document, such as indexes, are
+// left to be written again.
+func RemovePages(options *VisitorConfigOptions, doc *Document) error {
+	if !doc.HasClass() {
+		return nil
+	}
+
+	class := &doc.Blocks[0]
+	page := class.Name + options.Flavor.Extension
+
+	if options.Flavor.Format == "man" {
+		man := ManVisitor{Classes: options.ManClasses, Annotation: options.ManAnnotation}
+		if !man.selects(doc) {
+			return nil
+		}
+
+		section := options.ManSection
+		if section == "" {
+			section = "1"
+		}
+		page = commandName(class) + "." + section
+	}
+
+	path := filepath.Join(options.OutputDirectory, page)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return &FileError{Op: "remove", Path: path, Err: errors.Unwrap(err)}
+	}
+
+	return nil
+}
+
+// collectSymbols reads every document, and builds the map of every symbol
+// they declare (or link to, in external libraries).
+func collectSymbols(options *VisitorConfigOptions, docs chan *Document) ([]*Document, SymbolMap) {
+	var documents []*Document
+	for doc := range docs {
+		documents = append(documents, doc)
+	}
+
+	// Documents arrive in whatever order they're parsed in, so sort them to
+	// make sure that colliding symbols resolve the same way on every run
+	sort.Slice(documents, func(i, j int) bool {
+		return documents[i].Address < documents[j].Address
+	})
+
+	// The symbol visitor is special in that we want to visit _every_ document
+	// with this visitor before proceeding
+	symbolVisitor := SymbolVisitor{Symbols: NewSymbolMap()}
+	for _, doc := range documents {
+		symbolVisitor.visit(doc)
+	}
+
+	if len(options.ExternalLibraries) > 0 {
+		externalVisitor := ExternalLinkVisitor{Libraries: options.ExternalLibraries, Symbols: symbolVisitor.Symbols}
+		for _, doc := range documents {
+			externalVisitor.visit(doc)
+		}
+	}
+
+	return documents, symbolVisitor.Symbols
+}
+
+func makeOutputVisitor(options *VisitorConfigOptions, symbols SymbolMap) (OutputVisitor, error) {
+	if options.Flavor.Format == "json" {
+		return &JSONVisitor{OutputDirectory: options.OutputDirectory, SkipPrivateDefs: options.SkipPrivateDefs}, nil
+	}
+
+	if options.Flavor.Format == "html" {
+		templates, err := LoadHTMLTemplates(options.Flavor, options.TemplateDirectory)
+		if err != nil {
+			return nil, err
 		}
+
+		return &HTMLVisitor{
+			OutputDirectory:  options.OutputDirectory,
+			SkipPrivateDefs:  options.SkipPrivateDefs,
+			FlagUndocumented: options.FlagUndocumented,
+			Symbols:          symbols,
+			Templates:        templates,
+			Flavor:           options.Flavor,
+		}, nil
+	}
+
+	templates, err := LoadTemplates(options.Flavor, options.TemplateDirectory)
+	if err != nil {
+		return nil, err
 	}
+
+	if options.Flavor.Format == "man" {
+		section := options.ManSection
+		if section == "" {
+			section = "1"
+		}
+
+		return &ManVisitor{
+			OutputDirectory: options.OutputDirectory,
+			Section:         section,
+			Classes:         options.ManClasses,
+			Annotation:      options.ManAnnotation,
+			Symbols:         symbols,
+			Templates:       templates,
+			Flavor:          options.Flavor,
+		}, nil
+	}
+
+	return &MarkdownVisitor{
+		OutputDirectory:  options.OutputDirectory,
+		SkipPrivateDefs:  options.SkipPrivateDefs,
+		WriteIndex:       options.WriteIndex,
+		FlagUndocumented: options.FlagUndocumented,
+		Symbols:          symbols,
+		Templates:        templates,
+		Flavor:           options.Flavor,
+	}, nil
 }
 
 type Visitor interface {
-	visit(*Document) (bool, string)
+	visit(*Document) error
 }
 
+// An OutputVisitor is a Visitor which writes a page for each document. Once
+// every document has been visited, finish writes any files which cover all
+// of them, such as indexes.
+type OutputVisitor interface {
+	Visitor
+	finish(docs []*Document) error
+}
+
+// The SymbolVisitor records every symbol under its qualified name (i.e.
+// "com.example.Foo#bar(int)"), and under its simple name ("Foo#bar(int)") as
+// long as no class in another package shares it.
 type SymbolVisitor struct {
-	Symbols map[string]Symbol
+	Symbols SymbolMap
+
+	// Simple names declared in more than one package
+	ambiguous map[string]bool
 }
 
-func (v *SymbolVisitor) visit(doc *Document) (err bool, description string) {
-	err = false
-	description = ""
+func (v *SymbolVisitor) addSimpleName(name string, symbol Symbol) {
+	if v.ambiguous[name] {
+		return
+	}
+
+	if existing, ok := v.Symbols.Lookup(name); ok && existing.Package != symbol.Package {
+		if v.ambiguous == nil {
+			v.ambiguous = make(map[string]bool)
+		}
+
+		v.ambiguous[name] = true
+		v.Symbols.Delete(name)
+		return
+	}
+
+	v.Symbols.Add(name, symbol)
+}
 
+func (v *SymbolVisitor) visit(doc *Document) error {
 	for i, block := range doc.Blocks {
 		symbol := Symbol{Type: block.Type, Package: doc.Package, Name: block.Name, QualifiedName: block.Name}
 		if i == 0 {
 			symbol.Location = block.Name
-			v.Symbols[block.Name] = symbol
-			v.Symbols[doc.Package+"."+block.Name] = symbol
+			v.addSimpleName(block.Name, symbol)
+			v.Symbols.Add(doc.Package+"."+block.Name, symbol)
 		} else {
 			// First, put together the symbol's qualified name
 			qualifiedName := block.Name
@@ -77,7 +273,7 @@ func (v *SymbolVisitor) visit(doc *Document) (err bool, description string) {
 				numArgs := len(block.Arguments)
 				// For each argument, add to the symbol name
 				for i, val := range block.Arguments {
-					qualifiedName += val.Type
+					qualifiedName += erasure(val.Type)
 					if i < numArgs-1 {
 						qualifiedName += ","
 					}
@@ -93,117 +289,97 @@ func (v *SymbolVisitor) visit(doc *Document) (err bool, description string) {
 
 			// If the vague, argument-less symbol already exists in the map
 			// we want to only insert the exact symbol name below.
-			if _, ok := v.Symbols[symbolName]; !ok {
-				v.Symbols[symbolName] = symbol
-				v.Symbols[doc.Package+"."+symbolName] = symbol
+			if _, ok := v.Symbols.Lookup(doc.Package + "." + symbolName); !ok {
+				v.addSimpleName(symbolName, symbol)
+				v.Symbols.Add(doc.Package+"."+symbolName, symbol)
 			}
 
 			// Generate Qualified Name
 			symbolName = doc.Blocks[0].Name + "#" + qualifiedName
-			v.Symbols[symbolName] = symbol
-			v.Symbols[doc.Package+"."+symbolName] = symbol
+			v.addSimpleName(symbolName, symbol)
+			v.Symbols.Add(doc.Package+"."+symbolName, symbol)
 		}
 	}
 
-	return
+	return nil
 }
 
 // The MarkdownVisitor is responsible for emitting a markdown document for
-// each Document.
+// each Document, or an AsciiDoc document when given the asciidoc flavor.
 type MarkdownVisitor struct {
-	OutputDirectory string
-	SkipPrivateDefs bool
-	Symbols         map[string]Symbol
+	OutputDirectory  string
+	SkipPrivateDefs  bool
+	WriteIndex       bool
+	FlagUndocumented bool
+	Symbols          SymbolMap
+	Templates        *template.Template
+	Flavor           *Flavor
 }
 
-func (m *MarkdownVisitor) visit(doc *Document) (err bool, description string) {
-	err = false
-	description = ""
-	needs_newline := false
-
-	if m.SkipPrivateDefs && doc.Blocks[0].Attributes["visibility"] == "private" {
-		return
+func (m *MarkdownVisitor) visit(doc *Document) error {
+	if m.SkipPrivateDefs {
+		if doc.Blocks[0].Attributes["visibility"] == "private" {
+			return nil
+		}
+		doc = doc.withoutPrivate()
 	}
 
-	f, createErr := os.Create(filepath.Join(m.OutputDirectory, doc.Blocks[0].Name+".md"))
-	if createErr != nil {
-		err = true
-		description = createErr.Error()
-		return
+	var page bytes.Buffer
+	data := makePageData(doc, m.Symbols, m.Flavor)
+	if m.FlagUndocumented {
+		data.flagUndocumented()
 	}
-	defer f.Close()
-
-	for i, v := range doc.Blocks {
-		heading := "### "
-		sectionName := "`" + v.Definition + "` {#" + v.QualifiedName + "}"
-
-		if i == 0 {
-			heading = "# "
-			sectionName = v.Name
-		}
-
-		f.WriteString(heading + sectionName + "\n\n")
 
-		// Write out the definition separately if this is the first block
-		if i == 0 {
-			if doc.Package != "" {
-				f.WriteString("```java\n")
-				f.WriteString("import " + doc.Package + "." + v.Name + "\n```\n\n")
-			}
-
-			f.WriteString("## Definition\n\n")
-			f.WriteString("```java\n" + v.Definition + "\n```\n\n")
-
-			f.WriteString("## Overview\n\n")
-		}
+	if err := m.Templates.ExecuteTemplate(&page, "class", data); err != nil {
+		return err
+	}
 
-		// Before writing out content, write out any deprecated admonitions
-		if ret, found := v.Tags["@deprecated"]; found {
-			f.WriteString(":::caution Deprecated\n\n")
-			f.WriteString(ret.Interpolate(doc, m.Symbols, "") + "\n\n")
-			f.WriteString(":::\n\n")
+	fileName := doc.Blocks[0].Name + m.Flavor.Extension
+
+	// Report anything which would break the site build, rather than leaving
+	// it to be discovered later
+	if m.Flavor.MDX {
+		for _, err := range ValidateMDX(page.String()) {
+			mdxErr := err.(*MDXError)
+			logger.Report(logger.Diagnostic{
+				Level:   logger.LOG_LEVEL_WARN,
+				Code:    "invalid-mdx",
+				File:    filepath.Join(m.OutputDirectory, fileName),
+				Line:    mdxErr.Line,
+				Column:  1,
+				Message: mdxErr.Message,
+			})
 		}
+	}
 
-		f.WriteString(v.Text.Interpolate(doc, m.Symbols, ""))
-		f.WriteString("\n\n")
+	return writeIfChanged(filepath.Join(m.OutputDirectory, fileName), page.Bytes())
+}
 
-		if len(v.Arguments) > 0 {
-			f.WriteString("**Parameters:**" + "\n\n")
-			needs_newline = true
+// finish emits an index page listing every document if one was asked for,
+// and any additional files required by the flavor, such as navigation
+// configuration.
+func (m *MarkdownVisitor) finish(docs []*Document) error {
+	if m.WriteIndex {
+		if err := m.writeTemplate("index"+m.Flavor.Extension, "index", docs); err != nil {
+			return err
 		}
+	}
 
-		var paramOrder []string
-		resolvedParams := map[string]string{}
-		// First iterate over any arguments, detecting undocumented fields in the process
-		for _, value := range v.Arguments {
-			paramOrder = append(paramOrder, value.Name)
-			if description, found := v.Params[value.Name]; found {
-				resolvedParams[value.Name] = description.Interpolate(doc, m.Symbols, "")
-			} else {
-				resolvedParams[value.Name] = "*Undocumented*"
-			}
-		}
-		// Now iterate over anything "extra" in our params
-		for k, v := range v.Params {
-			if _, found := resolvedParams[k]; !found {
-				paramOrder = append(paramOrder, k)
-				resolvedParams[k] = v.Interpolate(doc, m.Symbols, "")
-			}
-		}
-		// Finally, write out all params
-		for _, p := range paramOrder {
-			f.WriteString("* `" + p + "` - " + resolvedParams[p] + "\n")
+	for file, name := range m.Flavor.Extras {
+		if err := m.writeTemplate(file, name, docs); err != nil {
+			return err
 		}
+	}
 
-		if ret, found := v.Tags["@return"]; found {
-			f.WriteString("\n**Returns:** " + ret.Interpolate(doc, m.Symbols, "") + "\n\n")
-			needs_newline = true
-		}
+	return nil
+}
 
-		if needs_newline {
-			f.WriteString("\n")
-		}
-		needs_newline = false
+func (m *MarkdownVisitor) writeTemplate(file string, name string, docs []*Document) error {
+	var page bytes.Buffer
+	err := m.Templates.ExecuteTemplate(&page, name, makeIndexData(docs, m.Symbols, m.Flavor, m.SkipPrivateDefs))
+	if err != nil {
+		return err
 	}
-	return
+
+	return writeIfChanged(filepath.Join(m.OutputDirectory, file), page.Bytes())
 }
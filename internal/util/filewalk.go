@@ -8,7 +8,6 @@ package util
 
 import (
 	"io/ioutil"
-	"log"
 	"path/filepath"
 	"strings"
 )
@@ -16,13 +15,18 @@ import (
 type SearchContext struct {
 	Root  string
 	Files chan string
+
+	// The error with each directory which couldn't be read. The list is
+	// complete once Files is closed.
+	Errors []error
 }
 
 func (ctx *SearchContext) discover(directory string) {
 	files, err := ioutil.ReadDir(directory)
 
 	if err != nil {
-		log.Fatal(err)
+		ctx.Errors = append(ctx.Errors, err)
+		return
 	}
 
 	for _, file := range files {
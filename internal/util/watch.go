@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package util
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Watcher reports changes to the Java files under a directory.
+type Watcher struct {
+	Root    string
+	Changes chan []string // Each batch of files which were created, changed or removed, and directories which were removed
+}
+
+// How long to wait for more changes before reporting a batch, since saving a
+// file (or checking out a branch) often makes several changes at once
+const settleTime = 100 * time.Millisecond
+
+// How often the polling watcher looks for changes
+const pollInterval = 500 * time.Millisecond
+
+// Watch starts watching the Java files under root. Where the platform can
+// notify us of changes, it does, and otherwise the files are polled.
+func Watch(root string) *Watcher {
+	w := &Watcher{
+		Root:    root,
+		Changes: make(chan []string),
+	}
+
+	paths := make(chan string)
+	if err := w.notify(paths); err != nil {
+		go w.poll(paths, w.scan())
+	}
+	go w.batch(paths)
+
+	return w
+}
+
+// batch collects changed files into batches, sending each once no more
+// changes have been seen for a while.
+func (w *Watcher) batch(paths chan string) {
+	changed := make(map[string]bool)
+	settled := time.NewTimer(settleTime)
+	settled.Stop()
+
+	for {
+		select {
+		case path := <-paths:
+			changed[path] = true
+			settled.Reset(settleTime)
+		case <-settled.C:
+			var batch []string
+			for path := range changed {
+				batch = append(batch, path)
+			}
+			sort.Strings(batch)
+
+			changed = make(map[string]bool)
+			w.Changes <- batch
+		}
+	}
+}
+
+// A stamp is what the polling watcher knows of a file, to tell whether it
+// has changed.
+type stamp struct {
+	modified time.Time
+	size     int64
+}
+
+// scan returns the stamp of every Java file under the root.
+func (w *Watcher) scan() map[string]stamp {
+	stamps := make(map[string]stamp)
+	filepath.WalkDir(w.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isJavaFile(path) {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil {
+			stamps[path] = stamp{info.ModTime(), info.Size()}
+		}
+		return nil
+	})
+	return stamps
+}
+
+// poll scans the root on an interval, sending any file which changed since
+// the previous scan. The first is taken before polling starts, so that no
+// change made after Watch returns is missed.
+func (w *Watcher) poll(paths chan string, previous map[string]stamp) {
+	for {
+		time.Sleep(pollInterval)
+		current := w.scan()
+
+		for path, s := range current {
+			if previous[path] != s {
+				paths <- path
+			}
+		}
+
+		for path := range previous {
+			if _, found := current[path]; !found {
+				paths <- path
+			}
+		}
+
+		previous = current
+	}
+}
+
+// javaFiles sends every Java file under directory.
+func javaFiles(directory string, paths chan string) {
+	filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && isJavaFile(path) {
+			paths <- path
+		}
+		return nil
+	})
+}
+
+func isJavaFile(path string) bool {
+	return strings.HasSuffix(path, ".java")
+}
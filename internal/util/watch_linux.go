@@ -0,0 +1,120 @@
+//go:build linux
+
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package util
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// notify watches every directory under the root with inotify, sending the
+// Java files which change. An error is returned if inotify can't be used,
+// i.e. because we've run out of watches.
+func (w *Watcher) notify(paths chan string) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+
+	directories := make(map[int]string)
+	addWatches := func(root string) error {
+		return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return err
+			}
+
+			wd, err := syscall.InotifyAddWatch(fd, path, inotifyMask)
+			if err != nil {
+				return err
+			}
+
+			directories[wd] = path
+			return nil
+		})
+	}
+
+	if err = addWatches(w.Root); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+
+	go func() {
+		buffer := make([]byte, 64*1024)
+
+		for {
+			n, err := syscall.Read(fd, buffer)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil || n <= 0 {
+				return
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+				nameBytes := buffer[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+				name := string(bytes.TrimRight(nameBytes, "\x00"))
+				offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+				// Events were dropped, so anything may have changed
+				if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+					javaFiles(w.Root, paths)
+					continue
+				}
+
+				directory, found := directories[int(event.Wd)]
+				if !found {
+					continue
+				}
+
+				if event.Mask&syscall.IN_IGNORED != 0 {
+					delete(directories, int(event.Wd))
+					continue
+				}
+
+				path := filepath.Join(directory, name)
+
+				// Watch new directories too, along with whatever was already
+				// in them by the time we did
+				if event.Mask&syscall.IN_ISDIR != 0 {
+					if event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+						addWatches(path)
+						javaFiles(path, paths)
+					}
+
+					// A directory moved out from under the root takes its
+					// files with it, without an event for each of them
+					if event.Mask&syscall.IN_MOVED_FROM != 0 {
+						for wd, watched := range directories {
+							if watched == path || strings.HasPrefix(watched, path+string(filepath.Separator)) {
+								syscall.InotifyRmWatch(fd, uint32(wd))
+								delete(directories, wd)
+							}
+						}
+						paths <- path
+					}
+					continue
+				}
+
+				if isJavaFile(path) {
+					paths <- path
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNotifyingWatcher(t *testing.T) {
+	root := t.TempDir()
+	w := Watch(root)
+
+	// Files in new directories are watched, along with the directories
+	directory := filepath.Join(root, "nested")
+	if err := os.Mkdir(directory, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	created := filepath.Join(directory, "Created.java")
+	writeFile(t, created, "class Created {}")
+
+	if changed := nextChanges(t, w); !reflect.DeepEqual(changed, []string{created}) {
+		t.Errorf("got %v, wanted %s", changed, created)
+	}
+
+	// A directory moved away is sent in place of the files it held
+	if err := os.Rename(directory, filepath.Join(t.TempDir(), "moved")); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed := nextChanges(t, w); !reflect.DeepEqual(changed, []string{directory}) {
+		t.Errorf("got %v, wanted %s", changed, directory)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package util
+
+import "errors"
+
+// notify isn't supported on this platform, so files are always polled.
+func (w *Watcher) notify(paths chan string) error {
+	return errors.New("filesystem notifications aren't supported")
+}
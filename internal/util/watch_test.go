@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2023, Dana Burkart <dana.burkart@gmail.com>
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// nextChanges returns the next batch of changes the watcher sends.
+func nextChanges(t *testing.T, w *Watcher) []string {
+	t.Helper()
+
+	select {
+	case changed := <-w.Changes:
+		return changed
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for changes")
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPollingWatcher(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "Existing.java")
+	writeFile(t, existing, "class Existing {}")
+
+	w := &Watcher{Root: root, Changes: make(chan []string)}
+	paths := make(chan string)
+	go w.poll(paths, w.scan())
+	go w.batch(paths)
+
+	// Files which are created or changed together are sent together, and
+	// anything which isn't Java is ignored
+	created := filepath.Join(root, "Created.java")
+	writeFile(t, created, "class Created {}")
+	writeFile(t, existing, "class Existing { int changed; }")
+	writeFile(t, filepath.Join(root, "notes.txt"), "not Java")
+
+	if changed := nextChanges(t, w); !reflect.DeepEqual(changed, []string{created, existing}) {
+		t.Errorf("got %v, wanted %s and %s", changed, created, existing)
+	}
+
+	if err := os.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed := nextChanges(t, w); !reflect.DeepEqual(changed, []string{existing}) {
+		t.Errorf("got %v, wanted %s", changed, existing)
+	}
+}
@@ -49,6 +49,16 @@
 
       }
 
+      /**
+       * Link with a label: {@link JavaClass#doSomething(long) the thing to do}
+       * Link with a label spanning multiple lines: {@link #overloadedMethod(int, int) the
+       * overloaded method}
+       * Link (plain) with a label: {@linkplain JavaClass a class}
+       */
+      public void testLinkLabels() {
+
+      }
+
       /**
        * This method will be overloaded below.
        *
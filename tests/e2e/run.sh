@@ -2,7 +2,7 @@
 
 OUTPUT_DIR=$(mktemp -d)
 
-go run cmd/javadoc2md/main.go -input tests/e2e/input -output $OUTPUT_DIR
+go run ./cmd/javadoc2md -input tests/e2e/input -output $OUTPUT_DIR
 
 if [[ $SHOULD_REBASE ]]; then
 	rm tests/e2e/expectations/*